@@ -0,0 +1,335 @@
+package chainist
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessRecord is the record of a request written to access logs.
+type AccessRecord struct {
+	// Time is the time when the request was received.
+	Time time.Time
+
+	// Method is the request method.
+	Method string
+
+	// Path is the path of the request URL.
+	Path string
+
+	// URI is the request target of the request line, e.g. "/users?page=2".
+	URI string
+
+	// Proto is the protocol version, e.g. "HTTP/1.1".
+	Proto string
+
+	// Route is the pattern of the route which matched the request.
+	// See RoutePattern().
+	Route string
+
+	// Status is the status code of the response.
+	// This is 0 if the connection was hijacked without a response.
+	Status int
+
+	// Bytes is the number of bytes written to the response body.
+	Bytes int64
+
+	// Duration is the time taken to serve the request.
+	Duration time.Duration
+
+	// RemoteAddr is the network address of the client.
+	RemoteAddr string
+
+	// RequestID is the ID of the request given by RequestID() in the same chain.
+	// This is empty if RequestID() is not used.
+	RequestID string
+
+	// User is the user name of the basic authentication.
+	User string
+
+	// UserAgent is the User-Agent header of the request.
+	UserAgent string
+
+	// Referer is the Referer header of the request.
+	Referer string
+}
+
+// AccessLogSink writes access records created by the middleware of AccessLog().
+type AccessLogSink interface {
+	// LogAccess is called with the request and its record after the request was served.
+	LogAccess(r *http.Request, rec *AccessRecord)
+}
+
+// AccessLogSinkFunc is the function type which implements AccessLogSink.
+type AccessLogSinkFunc func(r *http.Request, rec *AccessRecord)
+
+// LogAccess calls f(r, rec).
+func (f AccessLogSinkFunc) LogAccess(r *http.Request, rec *AccessRecord) {
+	f(r, rec)
+}
+
+// AccessLogOptions is the options of AccessLog().
+type AccessLogOptions struct {
+	// Logger is the logger which records are written to.
+	// slog.Default() is used if it is nil.
+	// This is ignored when Sink is set.
+	Logger *slog.Logger
+
+	// Level is the level of records written to Logger.
+	Level slog.Level
+
+	// Sink is the sink which records are written to instead of Logger.
+	// CommonLogSink() and CombinedLogSink() write records in the text formats of Apache.
+	Sink AccessLogSink
+
+	// Sample decides whether the record is written.
+	// All records are written if it is nil.
+	// See SampleRate().
+	Sample func(rec *AccessRecord) bool
+
+	// ExcludePaths is the list of URL paths which are not logged.
+	// Patterns of path.Match() can be used, e.g. "/static/*".
+	ExcludePaths []string
+
+	// Skip reports whether the request is not logged.
+	Skip Predicate
+}
+
+/*
+AccessLog returns a middleware which writes a structured record per request.
+The record has the method, the path, the route, the status code, the number of bytes of the body,
+the duration, the remote address, the request ID and the user agent of the request.
+Records are written with slog by default. If nil is given as the options, the defaults are used.
+
+The response is obtained with ResponseInfo(), so the middleware can be placed anywhere in a chain
+and the record has what is written to the response by the whole chain when the middleware returns.
+
+    chain := chainist.NewChain(chainist.AccessLog(nil))
+
+    // or with options
+    chain := chainist.NewChain(chainist.AccessLog(&chainist.AccessLogOptions{
+        Logger:       logger,
+        Sample:       chainist.SampleRate(0.1),
+        ExcludePaths: []string{"/healthz", "/static/*"},
+    }))
+
+    // or in the Apache combined log format
+    chain := chainist.NewChain(chainist.AccessLog(&chainist.AccessLogOptions{
+        Sink: chainist.CombinedLogSink(os.Stdout),
+    }))
+*/
+func AccessLog(opts *AccessLogOptions) Middleware {
+	if opts == nil {
+		opts = &AccessLogOptions{}
+	}
+	sink := opts.Sink
+	if sink == nil {
+		sink = &slogSink{logger: opts.Logger, level: opts.Level}
+	}
+	skip := opts.Skip
+	if len(opts.ExcludePaths) > 0 {
+		exclude := PathGlob(opts.ExcludePaths...)
+		if skip != nil {
+			skip = Or(skip, exclude)
+		} else {
+			skip = exclude
+		}
+	}
+	sample := opts.Sample
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if next == nil {
+				return
+			}
+			if skip != nil && skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			w = recordWriter(w)
+			r, m := withRouteMatch(r)
+			completed := false
+			defer func() {
+				info, _ := ResponseInfo(w)
+				rec := newAccessRecord(r, info, start)
+				rec.Route = m.pattern
+				if !completed && !info.Committed() {
+					// the response will be written by the middleware which recovers the panic
+					rec.Status = http.StatusInternalServerError
+				}
+				if sample == nil || sample(rec) {
+					sink.LogAccess(r, rec)
+				}
+			}()
+			next.ServeHTTP(w, r)
+			completed = true
+		})
+	}
+}
+
+// newAccessRecord returns the record of the request served with the response.
+func newAccessRecord(r *http.Request, info ResponseRecord, start time.Time) *AccessRecord {
+	rec := &AccessRecord{
+		Time:       start,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		URI:        r.RequestURI,
+		Proto:      r.Proto,
+		Status:     info.Status,
+		Bytes:      info.Bytes,
+		Duration:   time.Since(start),
+		RemoteAddr: r.RemoteAddr,
+		RequestID:  RequestIDFrom(r.Context()),
+		UserAgent:  r.UserAgent(),
+		Referer:    r.Referer(),
+	}
+	if rec.URI == "" {
+		rec.URI = r.URL.RequestURI()
+	}
+	if rec.Status == 0 && !info.Hijacked {
+		// net/http responds 200 OK if handlers write nothing
+		rec.Status = http.StatusOK
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		rec.User = user
+	}
+	return rec
+}
+
+/*
+SampleRate returns a sampling function for AccessLogOptions.Sample
+which chooses records at random with the given rate between 0 and 1.
+Records of server errors are always chosen.
+
+    chainist.AccessLog(&chainist.AccessLogOptions{
+        Sample: chainist.SampleRate(0.01),
+    })
+*/
+func SampleRate(rate float64) func(rec *AccessRecord) bool {
+	return func(rec *AccessRecord) bool {
+		return rec.Status >= 500 || rand.Float64() < rate
+	}
+}
+
+// slogSink is the AccessLogSink which writes records with slog.
+type slogSink struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+func (s *slogSink) LogAccess(r *http.Request, rec *AccessRecord) {
+	logger := s.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx := r.Context()
+	if !logger.Enabled(ctx, s.level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", rec.Method),
+		slog.String("path", rec.Path),
+	}
+	if rec.Route != "" {
+		attrs = append(attrs, slog.String("route", rec.Route))
+	}
+	attrs = append(attrs,
+		slog.Int("status", rec.Status),
+		slog.Int64("bytes", rec.Bytes),
+		slog.Duration("duration", rec.Duration),
+		slog.String("remote_addr", rec.RemoteAddr),
+	)
+	if rec.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", rec.RequestID))
+	}
+	attrs = append(attrs, slog.String("user_agent", rec.UserAgent))
+	logger.LogAttrs(ctx, s.level, "access", attrs...)
+}
+
+/*
+CommonLogSink returns an AccessLogSink which writes records to w in the Common Log Format of Apache.
+
+    127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+*/
+func CommonLogSink(w io.Writer) AccessLogSink {
+	return &textSink{w: w}
+}
+
+/*
+CombinedLogSink returns an AccessLogSink which writes records to w in the Combined Log Format of Apache.
+This is the Common Log Format followed by the referer and the user agent.
+
+    127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"
+*/
+func CombinedLogSink(w io.Writer) AccessLogSink {
+	return &textSink{w: w, combined: true}
+}
+
+// textSink is the AccessLogSink which writes records in the text formats of Apache.
+type textSink struct {
+	mu       sync.Mutex
+	w        io.Writer
+	combined bool
+}
+
+func (s *textSink) LogAccess(r *http.Request, rec *AccessRecord) {
+	host := rec.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	size := "-"
+	if rec.Bytes > 0 {
+		size = strconv.FormatInt(rec.Bytes, 10)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %s [%s] \"%s %s %s\" %d %s",
+		orDash(host),
+		orDash(escapeLogField(rec.User)),
+		rec.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		escapeLogField(rec.Method),
+		escapeLogField(rec.URI),
+		escapeLogField(rec.Proto),
+		rec.Status,
+		size,
+	)
+	if s.combined {
+		fmt.Fprintf(&b, " \"%s\" \"%s\"", orDash(escapeLogField(rec.Referer)), orDash(escapeLogField(rec.UserAgent)))
+	}
+	b.WriteByte('\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = io.WriteString(s.w, b.String())
+}
+
+// orDash returns "-" for empty string.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escapeLogField escapes quotes, backslashes and non-printable characters
+// in the same way as Apache does so that log lines can not be forged.
+func escapeLogField(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
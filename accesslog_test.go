@@ -0,0 +1,208 @@
+package chainist
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureSink returns the sink which stores records into the slice.
+func captureSink(recs *[]*AccessRecord) AccessLogSink {
+	return AccessLogSinkFunc(func(r *http.Request, rec *AccessRecord) {
+		*recs = append(*recs, rec)
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	{
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, nil))
+		h := NewChain(AccessLog(&AccessLogOptions{Logger: logger}), RequestID(nil)).ChainFunc(handlerFunc1)
+		r := httptest.NewRequest(http.MethodGet, "/users?page=2", nil)
+		r.Header.Set("User-Agent", "test-agent")
+		r.Header.Set("X-Request-ID", "req-1")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		var v map[string]any
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &v))
+		assert.Equal(t, "INFO", v["level"])
+		assert.Equal(t, "access", v["msg"])
+		assert.Equal(t, "GET", v["method"])
+		assert.Equal(t, "/users", v["path"])
+		assert.Equal(t, 200.0, v["status"])
+		assert.Equal(t, 2.0, v["bytes"])
+		assert.Equal(t, "192.0.2.1:1234", v["remote_addr"])
+		assert.Equal(t, "req-1", v["request_id"])
+		assert.Equal(t, "test-agent", v["user_agent"])
+		assert.Contains(t, v, "duration")
+		assert.NotContains(t, v, "route")
+	}
+	{
+		// records are not written below the level of the logger
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		h := NewChain(AccessLog(&AccessLogOptions{Logger: logger})).ChainFunc(handlerFunc1)
+		serveRecorder(h)
+		assert.Equal(t, 0, buf.Len())
+	}
+	{
+		// the default logger is used
+		var buf bytes.Buffer
+		defer slog.SetDefault(slog.Default())
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		serveRecorder(NewChain(AccessLog(nil)).ChainFunc(handlerFunc1))
+		assert.Contains(t, buf.String(), "msg=access method=GET path=/ status=200 bytes=2")
+	}
+}
+
+func TestAccessLogRecord(t *testing.T) {
+	{
+		// placed at the middle of the chain
+		var recs []*AccessRecord
+		c := NewChain(writeMiddleware("a"), AccessLog(&AccessLogOptions{Sink: captureSink(&recs)}), writeMiddleware("b"))
+		h := c.ChainFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("created"))
+		})
+		w := serveRecorder(h)
+		assert.Equal(t, "abcreated", w.Body.String())
+		assert.Equal(t, 1, len(recs))
+		assert.Equal(t, http.StatusOK, recs[0].Status) // "a" committed the response
+		assert.Equal(t, int64(9), recs[0].Bytes)       // including what "a" wrote
+	}
+	{
+		var recs []*AccessRecord
+		c := NewChain(AccessLog(&AccessLogOptions{Sink: captureSink(&recs)}))
+		h := c.ChainFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Millisecond)
+			http.Error(w, "bad", http.StatusBadRequest)
+		})
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/users?x=1", nil)
+		r.SetBasicAuth("frank", "secret")
+		r.Header.Set("Referer", "http://example.com/")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		rec := recs[0]
+		assert.Equal(t, http.MethodPost, rec.Method)
+		assert.Equal(t, "/users", rec.Path)
+		assert.Equal(t, "http://example.com/users?x=1", rec.URI)
+		assert.Equal(t, "HTTP/1.1", rec.Proto)
+		assert.Equal(t, http.StatusBadRequest, rec.Status)
+		assert.Equal(t, int64(4), rec.Bytes)
+		assert.Equal(t, "frank", rec.User)
+		assert.Equal(t, "http://example.com/", rec.Referer)
+		assert.True(t, rec.Duration >= time.Millisecond)
+		assert.False(t, rec.Time.IsZero())
+	}
+	{
+		// nothing written
+		var recs []*AccessRecord
+		h := NewChain(AccessLog(&AccessLogOptions{Sink: captureSink(&recs)})).ChainFunc(func(w http.ResponseWriter, r *http.Request) {})
+		serveRecorder(h)
+		assert.Equal(t, http.StatusOK, recs[0].Status)
+		assert.Equal(t, int64(0), recs[0].Bytes)
+	}
+	{
+		// panics are logged as 500 and recovered by the outer middleware
+		var recs []*AccessRecord
+		c := NewChain(Recover(&testReporter{}), AccessLog(&AccessLogOptions{Sink: captureSink(&recs)}))
+		w := serveRecorder(c.ChainFunc(panicHandlerFunc))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, 1, len(recs))
+		assert.Equal(t, http.StatusInternalServerError, recs[0].Status)
+	}
+}
+
+func TestAccessLogRoute(t *testing.T) {
+	var recs []*AccessRecord
+	router := NewRouter(AccessLog(&AccessLogOptions{Sink: captureSink(&recs)}))
+	router.Get("/users/{id}", handlerFunc1)
+	serveRouter(router, http.MethodGet, "/users/1")
+	serveRouter(router, http.MethodGet, "/unknown")
+
+	// the router is wrapped by the middleware
+	outer := NewChain(AccessLog(&AccessLogOptions{Sink: captureSink(&recs)})).ChainFunc(router.ServeHTTP)
+	serveRouter(outer, http.MethodGet, "/users/2")
+
+	assert.Equal(t, 4, len(recs))
+	assert.Equal(t, "/users/{id}", recs[0].Route)
+	assert.Equal(t, "", recs[1].Route)
+	assert.Equal(t, http.StatusNotFound, recs[1].Status)
+	assert.Equal(t, "/users/{id}", recs[2].Route)
+	assert.Equal(t, "/users/{id}", recs[3].Route)
+}
+
+func TestAccessLogFilter(t *testing.T) {
+	var recs []*AccessRecord
+	opts := &AccessLogOptions{
+		Sink:         captureSink(&recs),
+		ExcludePaths: []string{"/healthz", "/static/*"},
+		Skip:         Method(http.MethodOptions),
+		Sample: func(rec *AccessRecord) bool {
+			return rec.Path != "/sampled-out"
+		},
+	}
+	h := NewChain(AccessLog(opts)).ChainFunc(handlerFunc1)
+	for _, target := range []string{"/healthz", "/static/app.js", "/sampled-out", "/static/css/app.css", "/users"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, "f1", w.Body.String())
+	}
+	serveRouter(h, http.MethodOptions, "/users")
+	assert.Equal(t, 2, len(recs))
+	assert.Equal(t, "/static/css/app.css", recs[0].Path)
+	assert.Equal(t, "/users", recs[1].Path)
+}
+
+func TestSampleRate(t *testing.T) {
+	ok := &AccessRecord{Status: http.StatusOK}
+	failed := &AccessRecord{Status: http.StatusBadGateway}
+	for i := 0; i < 100; i++ {
+		assert.False(t, SampleRate(0)(ok))
+		assert.True(t, SampleRate(1)(ok))
+		assert.True(t, SampleRate(0)(failed))
+	}
+}
+
+func TestLogSink(t *testing.T) {
+	rec := &AccessRecord{
+		Time:       time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60)),
+		Method:     http.MethodGet,
+		URI:        "/apache_pb.gif",
+		Proto:      "HTTP/1.0",
+		Status:     http.StatusOK,
+		Bytes:      2326,
+		RemoteAddr: "127.0.0.1:5678",
+		User:       "frank",
+		Referer:    "http://www.example.com/start.html",
+		UserAgent:  "Mozilla/4.08",
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	{
+		var buf bytes.Buffer
+		CommonLogSink(&buf).LogAccess(r, rec)
+		assert.Equal(t, `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`+"\n", buf.String())
+	}
+	{
+		var buf bytes.Buffer
+		CombinedLogSink(&buf).LogAccess(r, rec)
+		assert.Equal(t, `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`+"\n", buf.String())
+	}
+	{
+		// empty values and escaping
+		var buf bytes.Buffer
+		rec := *rec
+		rec.User, rec.Referer, rec.Bytes = "", "", 0
+		rec.UserAgent = "evil\" agent\n\\"
+		CombinedLogSink(&buf).LogAccess(r, &rec)
+		line := buf.String()
+		assert.True(t, strings.HasPrefix(line, "127.0.0.1 - - ["))
+		assert.True(t, strings.HasSuffix(line, `200 - "-" "evil\" agent\x0a\\"`+"\n"), line)
+	}
+}
@@ -0,0 +1,251 @@
+package chainist
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+/*
+Buffer returns a middleware which buffers the response written by succeeding handlers in memory.
+The buffered response is written to the client after all succeeding handlers returned,
+so the handlers can inspect and rewrite the body, the status code and the header with ResponseBuffer().
+
+limit is the maximum number of bytes to be buffered.
+Once the body exceeds the limit, buffered response is written out and the rest is streamed to the client.
+If the limit is less than or equal to 0, the size of buffer is not limited.
+Flushing the writer with http.Flusher also makes the response streamed.
+
+    chain := chainist.NewChain(chainist.Buffer(1 << 20))
+    chain.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+        if b, ok := chainist.ResponseBuffer(w); ok {
+            b.SetBody(bytes.ToUpper(b.Body()))
+        }
+    })
+*/
+func Buffer(limit int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if next == nil {
+				return
+			}
+			b := &BufferedResponse{ResponseWriter: w, limit: limit}
+			var bw http.ResponseWriter = b
+			if _, ok := w.(http.Hijacker); ok {
+				bw = &hijackBufferedResponse{b}
+			}
+			next.ServeHTTP(bw, r)
+			b.writeOut()
+		})
+	}
+}
+
+/*
+EnableBuffering makes the chain buffer responses in memory.
+Responses are written to the client after all the middleware and the handler function returned,
+so the middleware and the post-executable functions can rewrite them with ResponseBuffer().
+See Buffer() for the limit.
+
+    chain := chainist.NewChain()
+    chain.EnableBuffering(1 << 20)
+    chain.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+        if b, ok := chainist.ResponseBuffer(w); ok {
+            b.SetBody(minify(b.Body()))
+        }
+    })
+*/
+func (c *Chain) EnableBuffering(limit int) *Chain {
+	c, done := c.mutate()
+	defer done()
+	c.buffering = true
+	c.bufferLimit = limit
+	return c
+}
+
+/*
+DisableBuffering disables buffering enabled with EnableBuffering().
+
+    chain.DisableBuffering()
+*/
+func (c *Chain) DisableBuffering() *Chain {
+	c, done := c.mutate()
+	defer done()
+	c.buffering = false
+	c.bufferLimit = 0
+	return c
+}
+
+/*
+ResponseBuffer returns the response buffered by the middleware created with Buffer().
+false is returned if the response is not buffered.
+
+    chain.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+        b, ok := chainist.ResponseBuffer(w)
+        if !ok || b.Streaming() {
+            return
+        }
+        b.SetStatus(http.StatusOK)
+        w.Header().Set("X-Signature", sign(b.Body()))
+    })
+*/
+func ResponseBuffer(w http.ResponseWriter) (*BufferedResponse, bool) {
+	if b, ok := findWriter[interface{ buffered() *BufferedResponse }](w); ok {
+		return b.buffered(), true
+	}
+	return nil, false
+}
+
+// BufferedResponse is the http.ResponseWriter which buffers the response in memory.
+// This is created by the middleware returned by Buffer().
+type BufferedResponse struct {
+	http.ResponseWriter
+	limit     int
+	status    int
+	body      bytes.Buffer
+	streaming bool
+	hijacked  bool
+	rewritten bool
+}
+
+func (b *BufferedResponse) buffered() *BufferedResponse {
+	return b
+}
+
+// Status returns the status code of the buffered response.
+// 0 is returned if the status code is not written yet.
+func (b *BufferedResponse) Status() int {
+	return b.status
+}
+
+// SetStatus replaces the status code of the buffered response.
+// This has no effect once the response is streamed.
+func (b *BufferedResponse) SetStatus(code int) {
+	if b.streaming {
+		return
+	}
+	b.status = code
+}
+
+// Body returns the buffered response body.
+// The returned slice is valid only until the next modification of the buffer.
+func (b *BufferedResponse) Body() []byte {
+	return b.body.Bytes()
+}
+
+// SetBody replaces the buffered response body.
+// This has no effect once the response is streamed.
+func (b *BufferedResponse) SetBody(body []byte) {
+	if b.streaming {
+		return
+	}
+	b.body.Reset()
+	b.body.Write(body)
+	b.rewritten = true
+}
+
+// Streaming reports whether the response is no longer buffered
+// because the body exceeded the limit or the writer was flushed.
+func (b *BufferedResponse) Streaming() bool {
+	return b.streaming
+}
+
+func (b *BufferedResponse) WriteHeader(code int) {
+	if b.streaming {
+		b.ResponseWriter.WriteHeader(code)
+		return
+	}
+	// informational responses are not buffered
+	if code < 200 && code != http.StatusSwitchingProtocols {
+		b.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *BufferedResponse) Write(p []byte) (int, error) {
+	if b.streaming {
+		return b.ResponseWriter.Write(p)
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	if b.limit > 0 && b.body.Len()+len(p) > b.limit {
+		if err := b.stream(); err != nil {
+			return 0, err
+		}
+		return b.ResponseWriter.Write(p)
+	}
+	return b.body.Write(p)
+}
+
+// Flush switches to streaming and flushes the underlying writer. See FlushError().
+func (b *BufferedResponse) Flush() {
+	_ = b.FlushError()
+}
+
+// FlushError switches to streaming and flushes the underlying writer with http.ResponseController,
+// so that writers which can be flushed only through Unwrap() are also flushed.
+// http.ErrNotSupported is returned if the underlying writer can not be flushed.
+func (b *BufferedResponse) FlushError() error {
+	if err := b.stream(); err != nil {
+		return err
+	}
+	return http.NewResponseController(b.ResponseWriter).Flush()
+}
+
+func (b *BufferedResponse) Push(target string, opts *http.PushOptions) error {
+	if p, ok := b.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (b *BufferedResponse) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+// stream writes out the buffered response and switches to streaming.
+func (b *BufferedResponse) stream() error {
+	if b.streaming {
+		return nil
+	}
+	b.streaming = true
+	if b.status != 0 {
+		b.ResponseWriter.WriteHeader(b.status)
+	}
+	if b.body.Len() == 0 {
+		return nil
+	}
+	_, err := b.ResponseWriter.Write(b.body.Bytes())
+	b.body.Reset()
+	return err
+}
+
+// writeOut writes the buffered response to the client.
+// Content-Length is corrected if it is set and the body has been rewritten.
+func (b *BufferedResponse) writeOut() {
+	if b.streaming || b.hijacked {
+		return
+	}
+	if b.rewritten && b.ResponseWriter.Header().Get("Content-Length") != "" {
+		b.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(b.body.Len()))
+	}
+	_ = b.stream()
+}
+
+type hijackBufferedResponse struct {
+	*BufferedResponse
+}
+
+func (b *hijackBufferedResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := b.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		b.hijacked = true
+		b.body.Reset()
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,200 @@
+package chainist
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveRecorder(h http.Handler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestBuffer(t *testing.T) {
+	{
+		w := serveRecorder(Buffer(0)(nil))
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "", w.Body.String())
+	}
+	{
+		var b *BufferedResponse
+		h := Buffer(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "5")
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusAccepted)
+			if _, err := w.Write([]byte("hello")); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			var ok bool
+			b, ok = ResponseBuffer(w)
+			assert.True(t, ok)
+			assert.False(t, b.Streaming())
+			assert.Equal(t, http.StatusCreated, b.Status())
+			assert.Equal(t, "hello", string(b.Body()))
+
+			b.SetStatus(http.StatusTeapot)
+			b.SetBody([]byte("HELLO WORLD"))
+			w.Header().Set("X-Post", "added")
+		}))
+		w := serveRecorder(h)
+		assert.Equal(t, http.StatusTeapot, w.Code)
+		assert.Equal(t, "HELLO WORLD", w.Body.String())
+		assert.Equal(t, "11", w.Header().Get("Content-Length"))
+		assert.Equal(t, "added", w.Header().Get("X-Post"))
+		assert.True(t, b.Streaming())
+	}
+	{
+		h := Buffer(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte("abc"))
+			assert.NoError(t, err)
+			_, err = w.Write([]byte("def"))
+			assert.NoError(t, err)
+
+			b, _ := ResponseBuffer(w)
+			assert.True(t, b.Streaming())
+			b.SetStatus(http.StatusTeapot)
+			b.SetBody([]byte("ignored"))
+			w.WriteHeader(http.StatusTeapot)
+			assert.Equal(t, http.StatusOK, b.Status())
+		}))
+		w := serveRecorder(h)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "abcdef", w.Body.String())
+	}
+	{
+		h := Buffer(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte("abc"))
+			assert.NoError(t, err)
+			w.(http.Flusher).Flush()
+			_, err = w.Write([]byte("def"))
+			assert.NoError(t, err)
+			assert.ErrorIs(t, w.(http.Pusher).Push("/", nil), http.ErrNotSupported)
+		}))
+		w := serveRecorder(h)
+		assert.True(t, w.Flushed)
+		assert.Equal(t, "abcdef", w.Body.String())
+	}
+	{
+		// Flush follows Unwrap() of the underlying writer
+		h := Buffer(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte("abc"))
+			assert.NoError(t, err)
+			assert.NoError(t, http.NewResponseController(w).Flush())
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(&wrappingWriter{rec}, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, "abc", rec.Body.String())
+	}
+	{
+		// informational responses are passed through and
+		// header is not written when nothing is written
+		hw := &headerOnlyWriter{header: http.Header{}}
+		Buffer(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+		})).ServeHTTP(hw, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, []int{http.StatusEarlyHints}, hw.codes)
+	}
+	{
+		fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+		Buffer(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte("abc"))
+			assert.NoError(t, err)
+			assert.NoError(t, w.(http.Pusher).Push("/style.css", nil))
+			_, _, err = w.(http.Hijacker).Hijack()
+			assert.NoError(t, err)
+		})).ServeHTTP(fw, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.True(t, fw.hijacked)
+		assert.Equal(t, "/style.css", fw.pushed)
+		assert.Equal(t, "", fw.Body.String())
+	}
+	{
+		Buffer(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _, err := w.(http.Hijacker).Hijack()
+			assert.Error(t, err)
+		})).ServeHTTP(&hijackOnlyWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+}
+
+func TestResponseBuffer(t *testing.T) {
+	{
+		b, ok := ResponseBuffer(httptest.NewRecorder())
+		assert.False(t, ok)
+		assert.Nil(t, b)
+	}
+	{
+		Buffer(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, ok := ResponseBuffer(recordWriter(w))
+			assert.True(t, ok)
+			assert.NotNil(t, b)
+		})).ServeHTTP(&hijackOnlyWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+}
+
+func TestEnableBuffering(t *testing.T) {
+	{
+		c := NewChain()
+		c.EnableBuffering(10)
+		assert.True(t, c.buffering)
+		assert.Equal(t, 10, c.bufferLimit)
+		c.DisableBuffering()
+		assert.False(t, c.buffering)
+		assert.Equal(t, 0, c.bufferLimit)
+	}
+	{
+		c := NewChain().EnableBuffering(0)
+		c.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, _ := ResponseInfo(w)
+			assert.Equal(t, http.StatusOK, info.Status)
+
+			b, ok := ResponseBuffer(w)
+			assert.True(t, ok)
+			b.SetBody(bytes.ToUpper(b.Body()))
+			w.Header().Set("X-Length", "4")
+		})
+		c.AppendPostFunc(handlerFunc1)
+		s := httptest.NewServer(c.ChainFunc(handlerFunc2))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := io.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "4", res.Header.Get("X-Length"))
+		assert.Equal(t, "F2F1", string(body))
+	}
+	{
+		c := NewChain().EnableBuffering(1)
+		c.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ResponseBuffer(w)
+			assert.True(t, b.Streaming())
+		})
+		s := httptest.NewServer(c.ChainFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := io.Copy(w, strings.NewReader("streamed")); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := io.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, "streamed", string(body))
+	}
+}
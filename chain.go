@@ -1,6 +1,7 @@
 package chainist
 
 import (
+	"fmt"
 	"net/http"
 )
 
@@ -19,8 +20,10 @@ An example of basic definition of middleware is
             // you can write your code here
         })
     }
+
+Middleware is the instantiation of GenericMiddleware for http.Handler.
 */
-type Middleware func(h http.Handler) http.Handler
+type Middleware = GenericMiddleware[http.Handler]
 
 /*
 Chain is the struct for middleware chain.
@@ -31,11 +34,52 @@ type Chain struct {
 	// Middleware is the list of middleware.
 	// This can contain nil values but they are ignored
 	// when getting middleware chains with Chain() or ChainFunc().
+	// Modifying this directly is supported only for replacing middleware in place.
+	// Names of the middleware are forgotten if this is replaced with another slice,
+	// e.g. by appending, prepending or removing middleware, because they can not be tracked anymore.
+	// Ordering constraints declared with After() or Before() are kept, so Build() reports an error
+	// instead of ignoring them in that case.
 	Middleware []Middleware
 
 	// HandlerFunc is the http handler function at the edge of the chain.
 	// If it is not set before calling Chain() or ChainFunc(),
 	HandlerFunc http.HandlerFunc
+
+	// label is the label of the chain used in diagrams.
+	// See SetLabel().
+	label string
+
+	// joins is the number of chains joined to this chain.
+	joins int
+
+	// entries holds the metadata of middleware, such as names, in the same order as Middleware.
+	// synced is the slice of Middleware which entries are made for.
+	// It is used to detect direct modifications of Middleware. See validEntries().
+	entries []entry
+	synced  []Middleware
+
+	// edges holds the ordering constraints between named middleware.
+	// They are declared with After() or Before().
+	edges []edge
+
+	// buffering enables buffering of responses with the limit of bufferLimit.
+	// See EnableBuffering().
+	buffering   bool
+	bufferLimit int
+
+	// tracing enables tracing of middleware.
+	// See EnableTracing().
+	tracing     bool
+	traceReport func(r *http.Request, t *Trace)
+
+	// recovery enables recovery of panics.
+	// See EnableRecovery().
+	recovery      bool
+	panicReporter PanicReporter
+
+	// immutable makes the methods of the chain return a modified copy
+	// instead of modifying the chain. See Immutable().
+	immutable bool
 }
 
 /*
@@ -46,6 +90,7 @@ NewChain creates a new middleware chain.
 Middleware can be added at the time of creation of a chain.
 Middleware is a function with the signature of `func(h http.Handler) http.Handler`.
 If nil is contained in the given arguments, nil is returned.
+Use NewChainE to get the reason as an error.
 
     // handler1 is called at first, and handler3 at last
     chain := chainist.NewChain(handler1, handler2, handler3)
@@ -53,16 +98,34 @@ If nil is contained in the given arguments, nil is returned.
 func NewChain(ms ...Middleware) *Chain {
 	for _, m := range ms {
 		if m == nil {
-			// it might be better to return an error
 			return nil
 		}
 	}
 	c := &Chain{
-		Middleware: ms,
+		Middleware: append([]Middleware(nil), ms...),
 	}
 	return c
 }
 
+/*
+NewChainE creates a new middleware chain same as NewChain.
+Instead of returning nil, this returns an error wrapping ErrNilMiddleware
+if nil is contained in the given arguments.
+
+    chain, err := chainist.NewChainE(handler1, handler2, handler3)
+    if err != nil {
+        panic(err)
+    }
+*/
+func NewChainE(ms ...Middleware) (*Chain, error) {
+	for i, m := range ms {
+		if m == nil {
+			return nil, fmt.Errorf("%w: argument at position %d is nil", ErrNilMiddleware, i)
+		}
+	}
+	return NewChain(ms...), nil
+}
+
 /*
 Append middleware at the last of the chain.
 If nil is given, then the chain will be returned without adding it.
@@ -73,11 +136,7 @@ If nil is given, then the chain will be returned without adding it.
          .Append(handler3)
 */
 func (c *Chain) Append(m Middleware) *Chain {
-	if m == nil {
-		return c
-	}
-	c.Middleware = append(c.Middleware, m)
-	return c
+	return c.add(m, entry{})
 }
 
 /*
@@ -110,7 +169,7 @@ func (c *Chain) AppendPreFunc(f http.HandlerFunc) *Chain {
 		return c
 	}
 	h := &HandlerFuncWrapper{HandlerFunc: f}
-	return c.Append(h.PreMiddleware)
+	return c.add(h.PreMiddleware, entry{kind: KindPreFunc, fn: f})
 }
 
 /*
@@ -142,7 +201,105 @@ func (c *Chain) AppendPostFunc(f http.HandlerFunc) *Chain {
 		return c
 	}
 	h := &HandlerFuncWrapper{HandlerFunc: f}
-	return c.Append(h.PostMiddleware)
+	return c.add(h.PostMiddleware, entry{kind: KindPostFunc, fn: f})
+}
+
+/*
+AppendGuard appends a guard function which is executed before invoking succeeding middleware.
+Guard function must have the signature of `func(w http.ResponseWriter, r *http.Request) bool`.
+Succeeding middleware and the handler function are invoked only when the guard function returns true,
+so the guard function should write the response by itself when it returns false.
+If nil is given as guard function, then the chain will be returned as it is.
+
+If you pass `YourGuardFunc(w http.ResponseWriter, r *http.Request) bool` as an argument, it is treaded as the middleware of
+
+    func handler(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if !YourGuardFunc(w, r) {
+                // abort the chain
+                return
+            }
+            if next != nil {
+                next.ServeHTTP(w, r)
+            }
+        })
+    }
+
+Usage:
+
+    chain := chainist.NewChain()
+    chain.AppendGuard(func(w http.ResponseWriter, r *http.Request) bool {
+        if r.Header.Get("Authorization") == "" {
+            w.WriteHeader(http.StatusUnauthorized)
+            return false
+        }
+        return true
+    })
+*/
+func (c *Chain) AppendGuard(f GuardFunc) *Chain {
+	if f == nil {
+		return c
+	}
+	g := &GuardFuncWrapper{GuardFunc: f}
+	return c.add(g.Middleware, entry{kind: KindGuard, fn: f})
+}
+
+/*
+AppendRequestFunc appends a request function which is executed before invoking succeeding middleware.
+Request function must have the signature of `func(w http.ResponseWriter, r *http.Request) *http.Request`.
+The returned request is passed to succeeding middleware, and the given request is passed if nil is returned.
+If nil is given as request function, then the chain will be returned as it is.
+
+If you pass `YourRequestFunc(w http.ResponseWriter, r *http.Request) *http.Request` as an argument, it is treaded as the middleware of
+
+    func handler(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if nr := YourRequestFunc(w, r); nr != nil {
+                r = nr
+            }
+            if next != nil {
+                next.ServeHTTP(w, r)
+            }
+        })
+    }
+
+Usage:
+
+    chain := chainist.NewChain()
+    chain.AppendRequestFunc(func(w http.ResponseWriter, r *http.Request) *http.Request {
+        ctx := context.WithValue(r.Context(), tenantKey, r.Header.Get("X-Tenant"))
+        return r.WithContext(ctx)
+    })
+*/
+func (c *Chain) AppendRequestFunc(f RequestFunc) *Chain {
+	if f == nil {
+		return c
+	}
+	h := &RequestFuncWrapper{RequestFunc: f}
+	return c.add(h.Middleware, entry{kind: KindRequestFunc, fn: f})
+}
+
+/*
+AppendFinalPostFunc appends a post-executable handler function same as AppendPostFunc(),
+but the function receives the final request which reached the edge of the chain,
+such as the request replaced by request functions or middleware.
+If nil is given as handler function, then the chain will be returned as it is.
+
+Usage:
+
+    chain := chainist.NewChain()
+    chain.AppendFinalPostFunc(func(w http.ResponseWriter, r *http.Request) {
+        // r is the request which the handler function received
+        log.Println(r.URL.Path)
+    })
+    chain.AppendRequestFunc(rewriteURL)
+*/
+func (c *Chain) AppendFinalPostFunc(f http.HandlerFunc) *Chain {
+	if f == nil {
+		return c
+	}
+	h := &HandlerFuncWrapper{HandlerFunc: f}
+	return c.add(h.FinalPostMiddleware, entry{kind: KindPostFunc, fn: f})
 }
 
 /*
@@ -167,19 +324,7 @@ Usage:
     chain.Insert(handler5, 0)
 */
 func (c *Chain) Insert(m Middleware, i int) *Chain {
-	if m == nil {
-		return c
-	}
-	if len(c.Middleware) == 0 || i >= len(c.Middleware) {
-		c.Middleware = append(c.Middleware, m)
-	} else {
-		if i < 0 {
-			i = 0
-		}
-		c.Middleware = append(c.Middleware[:i+1], c.Middleware[i:]...)
-		c.Middleware[i] = m
-	}
-	return c
+	return c.insert(m, i, entry{})
 }
 
 /*
@@ -206,7 +351,7 @@ func (c *Chain) InsertPreFunc(f http.HandlerFunc, i int) *Chain {
 		return c
 	}
 	h := &HandlerFuncWrapper{HandlerFunc: f}
-	return c.Insert(h.PreMiddleware, i)
+	return c.insert(h.PreMiddleware, i, entry{kind: KindPreFunc, fn: f})
 }
 
 /*
@@ -233,7 +378,60 @@ func (c *Chain) InsertPostFunc(f http.HandlerFunc, i int) *Chain {
 		return c
 	}
 	h := &HandlerFuncWrapper{HandlerFunc: f}
-	return c.Insert(h.PostMiddleware, i)
+	return c.insert(h.PostMiddleware, i, entry{kind: KindPostFunc, fn: f})
+}
+
+/*
+Insert a guard function at designated number of chain.
+Guard function must have the signature of `func(w http.ResponseWriter, r *http.Request) bool`.
+If nil is given as guard function, the chain will be returned without inserting it.
+
+If the number less than 0 is given as the position, given guard function is added at the first of the chain.
+If the number grater than the length of middleware is given as the position, the given guard function is added at the last of the chain.
+
+    // create a middleware chain with the order of handler1,handler2,handler3
+    chain := chainist.NewChain(handler1, handler2, handler3)
+
+    // insert guardFunc between handler1 and handler2
+    // chain.Middleware[1] will be a middleware created with guardFunc
+    chain.InsertGuard(guardFunc, 1)
+*/
+func (c *Chain) InsertGuard(f GuardFunc, i int) *Chain {
+	if f == nil {
+		return c
+	}
+	g := &GuardFuncWrapper{GuardFunc: f}
+	return c.insert(g.Middleware, i, entry{kind: KindGuard, fn: f})
+}
+
+/*
+Insert a request function at designated number of chain.
+See AppendRequestFunc() for the request function and Insert() for the position.
+
+    // insert rewriteURL at the first of the chain
+    chain.InsertRequestFunc(rewriteURL, 0)
+*/
+func (c *Chain) InsertRequestFunc(f RequestFunc, i int) *Chain {
+	if f == nil {
+		return c
+	}
+	h := &RequestFuncWrapper{RequestFunc: f}
+	return c.insert(h.Middleware, i, entry{kind: KindRequestFunc, fn: f})
+}
+
+/*
+Insert a post-executable handler function which receives the final request at designated number of chain.
+See AppendFinalPostFunc() for the handler function and Insert() for the position.
+
+    // insert logRequest at the first of the chain
+    chain.InsertFinalPostFunc(logRequest, 0)
+*/
+func (c *Chain) InsertFinalPostFunc(f http.HandlerFunc, i int) *Chain {
+	if f == nil {
+		return c
+	}
+	h := &HandlerFuncWrapper{HandlerFunc: f}
+	return c.insert(h.FinalPostMiddleware, i, entry{kind: KindPostFunc, fn: f})
 }
 
 /*
@@ -249,6 +447,8 @@ If nil is contained in the arguments, they are ignored.
     chain.Extend(handler3, handler4)
 */
 func (c *Chain) Extend(ms ...Middleware) *Chain {
+	c, done := c.mutate()
+	defer done()
 	for _, m := range ms {
 		if m == nil {
 			continue
@@ -271,12 +471,14 @@ nil is ignored if contained in the arguments.
     chain.ExtendPreFunc(handlerFunc3, handlerFunc4)
 */
 func (c *Chain) ExtendPreFunc(fs ...http.HandlerFunc) *Chain {
+	c, done := c.mutate()
+	defer done()
 	for _, f := range fs {
 		if f == nil {
 			continue
 		}
 		h := &HandlerFuncWrapper{HandlerFunc: f}
-		c.Append(h.PreMiddleware)
+		c.add(h.PreMiddleware, entry{kind: KindPreFunc, fn: f})
 	}
 	return c
 }
@@ -294,12 +496,76 @@ nil is ignored if contained in the arguments.
     chain.ExtendPostFunc(handlerFunc3, handlerFunc4)
 */
 func (c *Chain) ExtendPostFunc(fs ...http.HandlerFunc) *Chain {
+	c, done := c.mutate()
+	defer done()
+	for _, f := range fs {
+		if f == nil {
+			continue
+		}
+		h := &HandlerFuncWrapper{HandlerFunc: f}
+		c.add(h.PostMiddleware, entry{kind: KindPostFunc, fn: f})
+	}
+	return c
+}
+
+/*
+ExtendGuard appends multiple guard functions at a time.
+This function append multiple guard functions at the end of the chain.
+nil is ignored if contained in the arguments.
+
+    // create a middleware chain
+    chain := chainist.NewChain(handler1, handler2)
+
+    // append middleware created with guardFunc1 and guardFunc2 with this order
+    chain.ExtendGuard(guardFunc1, guardFunc2)
+*/
+func (c *Chain) ExtendGuard(fs ...GuardFunc) *Chain {
+	c, done := c.mutate()
+	defer done()
+	for _, f := range fs {
+		if f == nil {
+			continue
+		}
+		g := &GuardFuncWrapper{GuardFunc: f}
+		c.add(g.Middleware, entry{kind: KindGuard, fn: f})
+	}
+	return c
+}
+
+/*
+ExtendRequestFunc appends multiple request functions at a time.
+nil is ignored if contained in the arguments.
+
+    chain.ExtendRequestFunc(attachTenant, rewriteURL)
+*/
+func (c *Chain) ExtendRequestFunc(fs ...RequestFunc) *Chain {
+	c, done := c.mutate()
+	defer done()
+	for _, f := range fs {
+		if f == nil {
+			continue
+		}
+		h := &RequestFuncWrapper{RequestFunc: f}
+		c.add(h.Middleware, entry{kind: KindRequestFunc, fn: f})
+	}
+	return c
+}
+
+/*
+ExtendFinalPostFunc appends multiple post-executable handler functions which receive the final request at a time.
+nil is ignored if contained in the arguments.
+
+    chain.ExtendFinalPostFunc(logRequest, recordMetrics)
+*/
+func (c *Chain) ExtendFinalPostFunc(fs ...http.HandlerFunc) *Chain {
+	c, done := c.mutate()
+	defer done()
 	for _, f := range fs {
 		if f == nil {
 			continue
 		}
 		h := &HandlerFuncWrapper{HandlerFunc: f}
-		c.Append(h.PostMiddleware)
+		c.add(h.FinalPostMiddleware, entry{kind: KindPostFunc, fn: f})
 	}
 	return c
 }
@@ -318,12 +584,17 @@ func (c *Chain) SetHandlerFunc(f http.HandlerFunc) *Chain {
 	if f == nil {
 		return c
 	}
+	c, done := c.mutate()
+	defer done()
 	c.HandlerFunc = f
 	return c
 }
 
 /*
 Join joins two chains.
+Names of the middleware in the joined chain are kept.
+If a name is already used in the chain, the middleware is joined without its name.
+The joined middleware is grouped with the label of the joined chain in diagrams.
 
     // create two chains with handlers.
     chain1 := chainist.NewChain(handler1, handler2)
@@ -338,7 +609,27 @@ func (c *Chain) Join(o *Chain) *Chain {
 	if o == nil {
 		return c
 	}
-	c.Middleware = append(c.Middleware, o.Middleware...)
+	c, done := c.mutate()
+	defer done()
+	c.syncEntries()
+	c.joins++
+	group := o.label
+	if group == "" {
+		group = fmt.Sprintf("joined chain %d", c.joins)
+	}
+	for i, m := range o.Middleware {
+		e := o.entryAt(i)
+		if c.Has(e.name) {
+			e.name = ""
+		}
+		if e.group == "" {
+			e.group = group
+		}
+		c.Middleware = append(c.Middleware, m)
+		c.entries = append(c.entries, e)
+		c.markSynced()
+	}
+	c.edges = append(c.edges, o.edges...)
 	return c
 }
 
@@ -360,9 +651,79 @@ func (c *Chain) Len() int {
 	return length
 }
 
+/*
+Clone returns a copy of the chain.
+The copy does not share any slices with the chain, so modifying one does not affect the other.
+The copy is immutable if the chain is immutable.
+
+    base := chainist.NewChain(handler1, handler2)
+
+    // base still has handler1,handler2
+    admin := base.Clone().Insert(adminHandler, 1)
+*/
+func (c *Chain) Clone() *Chain {
+	n := *c
+	n.Middleware = append([]Middleware(nil), c.Middleware...)
+	n.entries = append([]entry(nil), c.validEntries()...)
+	n.markSynced()
+	n.edges = append([]edge(nil), c.edges...)
+	return &n
+}
+
+/*
+Immutable returns an immutable copy of the chain.
+Methods of immutable chains which modify the chain, e.g. Append(), Insert(), Extend() and Join(),
+return a modified copy of the chain instead of modifying it.
+Copies never share slices with the original, so variants can be derived from a common base chain safely.
+Note that modifying Middleware directly is not prevented.
+
+    base := chainist.NewChain(handler1, handler2).Immutable()
+
+    // base is not modified
+    api := base.Append(authHandler)
+    web := base.Append(sessionHandler)
+*/
+func (c *Chain) Immutable() *Chain {
+	n := c.Clone()
+	n.immutable = true
+	return n
+}
+
+/*
+Mutable returns a mutable copy of the chain.
+Methods of mutable chains modify the chain itself.
+
+    chain := base.Mutable()
+    chain.Append(handler3)
+*/
+func (c *Chain) Mutable() *Chain {
+	n := c.Clone()
+	n.immutable = false
+	return n
+}
+
+// IsImmutable reports whether the chain is immutable. See Immutable().
+func (c *Chain) IsImmutable() bool {
+	return c.immutable
+}
+
+// mutate returns the chain to be modified by the methods of the chain.
+// For immutable chains, this returns a mutable copy and the function which makes it immutable again,
+// which is intended to be deferred so that nested method calls do not copy the chain again.
+func (c *Chain) mutate() (*Chain, func()) {
+	if !c.immutable {
+		return c, func() {}
+	}
+	n := c.Clone()
+	n.immutable = false
+	return n, func() { n.immutable = true }
+}
+
 /*
 Chain returns a new middleware chain.
 This function returns nil if there is no middleware and no handler function.
+This function panics if the ordering constraints can not be satisfied as ChainFunc() does.
+Use Build() to get the error instead.
 
 Usage:
 
@@ -392,6 +753,18 @@ If the given handler function is not nil, it is used instead of the chain.Handle
 If both given handler function and chain.HandlerFunc are nil, nil value is passed to the middleware. Under that situation,
 your middleware have to be coded to handle nil for the http.Handler given as the middleware's argument.
 
+Middleware is chained in the order computed by Resolve().
+Responses written through the returned handler are recorded, so the middleware can use ResponseInfo().
+The store of request-scoped values is installed, so the values set with Key.Set() are shared in the chain.
+If buffering is enabled with EnableBuffering(), the whole chain is wrapped by the middleware created with Buffer().
+If tracing is enabled with EnableTracing(), every middleware is wrapped to record its execution.
+If recovery is enabled with EnableRecovery(), the middleware created with Recover() is placed at the outermost.
+This function panics if the ordering constraints declared with After() or Before() can not be satisfied,
+i.e. a constraint refers to unknown middleware or the constraints have a cycle.
+Names of the middleware are required for the constraints, so this also panics if they are forgotten
+because of direct modifications of Middleware. Chains without constraints never panic for them.
+Use BuildFunc() to get the error instead, or call Resolve() in advance to check the constraints.
+
 Usage:
 
     // defining a middleware chain
@@ -424,12 +797,108 @@ func (c *Chain) ChainFunc(f http.HandlerFunc) http.Handler {
 		return nil
 	}
 
-	for i := range c.Middleware {
-		if c.Middleware[n-1-i] == nil {
-			continue
+	order, err := c.resolve()
+	if err != nil {
+		panic(err)
+	}
+	if h != nil {
+		h = finalRequestMiddleware(h)
+	}
+	g := &GenericChain[http.Handler]{Middleware: make([]Middleware, 0, len(order))}
+	for _, i := range order {
+		m := c.Middleware[i]
+		if c.tracing {
+			m = traceMiddleware(c.displayName(i), i, m)
 		}
-		h = c.Middleware[n-1-i](h)
+		g.Middleware = append(g.Middleware, m)
+	}
+	h = g.Then(h)
+	if h == nil {
+		return nil
+	}
+
+	if c.tracing {
+		h = traceRoot(c.traceReport, h)
 	}
+	h = recordMiddleware(h)
+	if c.buffering {
+		h = Buffer(c.bufferLimit)(h)
+	}
+	h = valuesMiddleware(h)
+	if c.recovery {
+		h = Recover(c.panicReporter)(h)
+	}
+	return h
+}
+
+/*
+Build returns a new middleware chain same as Chain() but reports misconfiguration as an error.
+This is the same as calling BuildFunc(nil).
+
+Usage:
+
+    chain := chainist.NewChain(handler1, handler2)
+    chain.SetHandlerFunc(handlerFuncAtEdge)
 
+    handler, err := chain.Build()
+    if err != nil {
+        panic(err)
+    }
+
+    // run http server
+    http.ListenAndServe(":8080", handler)
+*/
+func (c *Chain) Build() (http.Handler, error) {
+	return c.BuildFunc(nil)
+}
+
+/*
+BuildFunc returns a new middleware chain same as ChainFunc() but reports misconfiguration as an error.
+If the given handler function is not nil, it is used instead of the chain.HandlerFunc which is set with `SetHandlerFunc()`.
+
+Following errors are returned.
+
+  - ErrEmptyChain if the chain has neither middleware nor a handler function.
+  - ErrNoHandler if both the given handler function and chain.HandlerFunc are nil.
+  - ErrMissingDependency or ErrDependencyCycle if the ordering constraints can not be satisfied.
+
+Usage:
+
+    chain := chainist.NewChain(handler1, handler2)
+
+    handler, err := chain.BuildFunc(handlerFuncAtEdge)
+    if err != nil {
+        panic(err)
+    }
+*/
+func (c *Chain) BuildFunc(f http.HandlerFunc) (http.Handler, error) {
+	if f == nil {
+		f = c.HandlerFunc
+	}
+	if f == nil {
+		for _, m := range c.Middleware {
+			if m != nil {
+				return nil, ErrNoHandler
+			}
+		}
+		return nil, ErrEmptyChain
+	}
+	if _, err := c.Resolve(); err != nil {
+		return nil, err
+	}
+	return c.ChainFunc(f), nil
+}
+
+/*
+MustBuild is like Build but panics if the chain can not be built.
+This is intended to be used at the initialization of servers.
+
+    http.ListenAndServe(":8080", chain.MustBuild())
+*/
+func (c *Chain) MustBuild() http.Handler {
+	h, err := c.Build()
+	if err != nil {
+		panic(err)
+	}
 	return h
 }
@@ -659,3 +659,368 @@ func TestChainFunc(t *testing.T) {
 		assert.Equal(t, "f1f2f2", string(body))
 	}
 }
+
+func TestNewChainE(t *testing.T) {
+	{
+		c, err := NewChainE()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c, err := NewChainE(handler1.Middleware, nil)
+		assert.Nil(t, c)
+		assert.ErrorIs(t, err, ErrNilMiddleware)
+		assert.Contains(t, err.Error(), "position 1")
+	}
+	{
+		c, err := NewChainE(handler1.Middleware, handler2.Middleware)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler1.Middleware), funcPointer(c.Middleware[0]))
+		assert.Equal(t, funcPointer(handler2.Middleware), funcPointer(c.Middleware[1]))
+	}
+}
+
+func TestBuild(t *testing.T) {
+	{
+		c := NewChain()
+		h, err := c.Build()
+		assert.Nil(t, h)
+		assert.ErrorIs(t, err, ErrEmptyChain)
+	}
+	{
+		c := &Chain{
+			Middleware: []Middleware{nil},
+		}
+		h, err := c.Build()
+		assert.Nil(t, h)
+		assert.ErrorIs(t, err, ErrEmptyChain)
+	}
+	{
+		c := NewChain(handler1.Middleware)
+		h, err := c.Build()
+		assert.Nil(t, h)
+		assert.ErrorIs(t, err, ErrNoHandler)
+	}
+	{
+		c := NewChain(handler1.Middleware)
+		c.After("a", "b")
+		h, err := c.BuildFunc(handlerFunc1)
+		assert.Nil(t, h)
+		assert.ErrorIs(t, err, ErrMissingDependency)
+	}
+	{
+		c := NewChain().Append(handler1.Middleware).SetHandlerFunc(handlerFunc2)
+		h, err := c.Build()
+		assert.NoError(t, err)
+		s := httptest.NewServer(h)
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "h1f2", string(body))
+	}
+	{
+		c := NewChain().Append(handler1.Middleware).SetHandlerFunc(handlerFunc2)
+		h, err := c.BuildFunc(handlerFunc1)
+		assert.NoError(t, err)
+		s := httptest.NewServer(h)
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "h1f1", string(body))
+	}
+}
+
+func TestMustBuild(t *testing.T) {
+	{
+		c := NewChain(handler1.Middleware)
+		assert.PanicsWithError(t, ErrNoHandler.Error(), func() { c.MustBuild() })
+	}
+	{
+		c := NewChain(handler1.Middleware).SetHandlerFunc(handlerFunc1)
+		assert.NotPanics(t, func() { c.MustBuild() })
+	}
+}
+
+func guardFunc1(w http.ResponseWriter, _ *http.Request) bool {
+	w.WriteHeader(http.StatusForbidden)
+	return false
+}
+
+func guardFunc2(_ http.ResponseWriter, _ *http.Request) bool {
+	return true
+}
+
+func TestAppendGuard(t *testing.T) {
+	{
+		c := NewChain()
+		c.AppendGuard(nil)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c := NewChain()
+		c.AppendGuard(guardFunc1)
+		e := &GuardFuncWrapper{GuardFunc: guardFunc1}
+		assert.Equal(t, 1, len(c.Middleware))
+		assert.Equal(t, funcPointer(e.Middleware), funcPointer(c.Middleware[0]))
+	}
+	{
+		c := NewChain().AppendGuard(guardFunc2).AppendPostFunc(handlerFunc2)
+		s := httptest.NewServer(c.ChainFunc(handlerFunc1))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "f1f2", string(body))
+	}
+	{
+		c := NewChain().AppendGuard(guardFunc1).AppendPostFunc(handlerFunc2)
+		s := httptest.NewServer(c.ChainFunc(handlerFunc1))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 403, res.StatusCode)
+		assert.Equal(t, "", string(body))
+	}
+}
+
+func TestInsertGuard(t *testing.T) {
+	{
+		c := NewChain()
+		c.InsertGuard(nil, 0)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c := NewChain(handler1.Middleware)
+		c.InsertGuard(guardFunc1, 0)
+		e := &GuardFuncWrapper{GuardFunc: guardFunc1}
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, funcPointer(e.Middleware), funcPointer(c.Middleware[0]))
+		assert.Equal(t, funcPointer(handler1.Middleware), funcPointer(c.Middleware[1]))
+	}
+}
+
+func TestExtendGuard(t *testing.T) {
+	{
+		c := NewChain()
+		c.ExtendGuard(nil, nil)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c := NewChain()
+		c.ExtendGuard(guardFunc1, guardFunc2)
+		e := &GuardFuncWrapper{GuardFunc: guardFunc1}
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, funcPointer(e.Middleware), funcPointer(c.Middleware[0]))
+		assert.Equal(t, funcPointer(e.Middleware), funcPointer(c.Middleware[1]))
+	}
+}
+
+func TestClone(t *testing.T) {
+	c := NewChain().
+		AppendNamed("a", writeMiddleware("a")).
+		AppendNamed("b", writeMiddleware("b")).
+		After("a", "b").
+		SetLabel("base")
+	c.EnableBuffering(10)
+	n := c.Clone()
+	assert.Equal(t, c.Describe(), n.Describe())
+	assert.Equal(t, c.edges, n.edges)
+	assert.True(t, n.buffering)
+	assert.False(t, n.IsImmutable())
+
+	n.Replace("a", writeMiddleware("x"))
+	n.AppendNamed("c", writeMiddleware("c"))
+	n.Before("c", "b")
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 1, len(c.edges))
+	assert.Equal(t, "baf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+	assert.Equal(t, "cbxf1", serveRecorder(n.ChainFunc(handlerFunc1)).Body.String())
+}
+
+func TestImmutable(t *testing.T) {
+	base := NewChain(writeMiddleware("a"), writeMiddleware("b")).Immutable()
+	assert.True(t, base.IsImmutable())
+	{
+		c := base.Append(writeMiddleware("c"))
+		assert.NotSame(t, base, c)
+		assert.True(t, c.IsImmutable())
+		assert.Equal(t, 2, base.Len())
+		assert.Equal(t, 3, c.Len())
+	}
+	{
+		// derived variants do not corrupt one another
+		v1 := base.Insert(writeMiddleware("x"), 1)
+		v2 := base.Insert(writeMiddleware("y"), 1)
+		assert.Equal(t, "axbf1", serveRecorder(v1.ChainFunc(handlerFunc1)).Body.String())
+		assert.Equal(t, "aybf1", serveRecorder(v2.ChainFunc(handlerFunc1)).Body.String())
+		assert.Equal(t, "abf1", serveRecorder(base.ChainFunc(handlerFunc1)).Body.String())
+	}
+	{
+		c := base.Extend(writeMiddleware("c"), writeMiddleware("d")).
+			ExtendPreFunc(handlerFunc2).
+			Join(NewChain(writeMiddleware("e"))).
+			SetHandlerFunc(handlerFunc1).
+			EnableRecovery(nil)
+		assert.Equal(t, 2, base.Len())
+		assert.Nil(t, base.HandlerFunc)
+		assert.False(t, base.recovery)
+		assert.Equal(t, "abcdf2ef1", serveRecorder(c.Chain()).Body.String())
+	}
+	{
+		named := NewChain().AppendNamed("auth", writeMiddleware("auth")).Immutable()
+		c := named.InsertBefore("auth", writeMiddleware("before")).After("auth", "before")
+		assert.Equal(t, 1, named.Len())
+		assert.Equal(t, 0, len(named.edges))
+		assert.Equal(t, 2, c.Len())
+		c = named.Remove("auth")
+		assert.True(t, named.Has("auth"))
+		assert.False(t, c.Has("auth"))
+		c = named.Replace("auth", writeMiddleware("x"))
+		assert.Equal(t, "authf1", serveRecorder(named.ChainFunc(handlerFunc1)).Body.String())
+		assert.Equal(t, "xf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+	}
+	{
+		// no copies are made when nothing is modified
+		assert.Same(t, base, base.Append(nil))
+		assert.Same(t, base, base.Join(nil))
+	}
+	{
+		c := base.Mutable()
+		assert.False(t, c.IsImmutable())
+		assert.Same(t, c, c.Append(writeMiddleware("c")))
+		assert.Equal(t, 2, base.Len())
+	}
+}
+
+func TestChainAliasing(t *testing.T) {
+	ms := make([]Middleware, 2, 10)
+	ms[0], ms[1] = writeMiddleware("a"), writeMiddleware("b")
+	c1 := NewChain(ms...)
+	c2 := NewChain(ms...)
+	c1.Insert(writeMiddleware("x"), 1)
+	c2.Append(writeMiddleware("y"))
+	assert.Equal(t, "axbf1", serveRecorder(c1.ChainFunc(handlerFunc1)).Body.String())
+	assert.Equal(t, "abyf1", serveRecorder(c2.ChainFunc(handlerFunc1)).Body.String())
+}
+
+// tagRequestFunc returns a request function which appends the tag to the X-Tags header of the request.
+func tagRequestFunc(tag string) RequestFunc {
+	return func(w http.ResponseWriter, r *http.Request) *http.Request {
+		r2 := r.Clone(r.Context())
+		r2.Header.Set("X-Tags", r.Header.Get("X-Tags")+tag)
+		return r2
+	}
+}
+
+// tagsHandlerFunc writes the X-Tags header of the request.
+func tagsHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("[" + r.Header.Get("X-Tags") + "]"))
+}
+
+func TestAppendRequestFunc(t *testing.T) {
+	c := NewChain()
+	c.AppendRequestFunc(nil)
+	assert.Equal(t, 0, c.Len())
+	c.AppendRequestFunc(tagRequestFunc("a")).AppendRequestFunc(tagRequestFunc("b"))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, KindRequestFunc, c.Describe().Entries[0].Kind)
+	assert.Equal(t, "[ab]", serveRecorder(c.ChainFunc(tagsHandlerFunc)).Body.String())
+}
+
+func TestInsertRequestFunc(t *testing.T) {
+	c := NewChain()
+	c.InsertRequestFunc(nil, 0)
+	assert.Equal(t, 0, c.Len())
+	c.InsertRequestFunc(tagRequestFunc("a"), 0)
+	c.InsertRequestFunc(tagRequestFunc("b"), 0)
+	c.InsertRequestFunc(tagRequestFunc("c"), 99)
+	assert.Equal(t, "[bac]", serveRecorder(c.ChainFunc(tagsHandlerFunc)).Body.String())
+}
+
+func TestExtendRequestFunc(t *testing.T) {
+	c := NewChain()
+	c.ExtendRequestFunc(tagRequestFunc("a"), nil, tagRequestFunc("b"))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, "[ab]", serveRecorder(c.ChainFunc(tagsHandlerFunc)).Body.String())
+}
+
+func TestAppendFinalPostFunc(t *testing.T) {
+	var tags []string
+	post := func(w http.ResponseWriter, r *http.Request) {
+		tags = append(tags, r.Header.Get("X-Tags"))
+	}
+	{
+		c := NewChain()
+		c.AppendFinalPostFunc(nil)
+		assert.Equal(t, 0, c.Len())
+		c.AppendFinalPostFunc(post).AppendPostFunc(post).AppendRequestFunc(tagRequestFunc("a"))
+		assert.Equal(t, KindPostFunc, c.Describe().Entries[0].Kind)
+		// the request replaced by plain middleware is also given
+		c.Append(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, tagRequestFunc("b")(w, r))
+			})
+		})
+		assert.Equal(t, "[ab]", serveRecorder(c.ChainFunc(tagsHandlerFunc)).Body.String())
+		// post functions are executed in the reverse order
+		assert.Equal(t, []string{"", "ab"}, tags)
+	}
+	{
+		// the request of the innermost chain
+		tags = nil
+		inner := NewChain().AppendRequestFunc(tagRequestFunc("inner")).ChainFunc(tagsHandlerFunc)
+		c := NewChain().AppendFinalPostFunc(post).AppendRequestFunc(tagRequestFunc("outer"))
+		assert.Equal(t, "[outerinner]", serveRecorder(c.ChainFunc(inner.ServeHTTP)).Body.String())
+		assert.Equal(t, []string{"outerinner"}, tags)
+	}
+}
+
+func TestInsertFinalPostFunc(t *testing.T) {
+	var got string
+	c := NewChain().AppendRequestFunc(tagRequestFunc("a"))
+	c.InsertFinalPostFunc(nil, 0)
+	assert.Equal(t, 1, c.Len())
+	c.InsertFinalPostFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Tags")
+	}, 0)
+	serveRecorder(c.ChainFunc(tagsHandlerFunc))
+	assert.Equal(t, "a", got)
+}
+
+func TestExtendFinalPostFunc(t *testing.T) {
+	var got []string
+	post := func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Tags"))
+	}
+	c := NewChain().ExtendFinalPostFunc(post, nil, post).AppendRequestFunc(tagRequestFunc("a"))
+	assert.Equal(t, 3, c.Len())
+	serveRecorder(c.ChainFunc(tagsHandlerFunc))
+	assert.Equal(t, []string{"a", "a"}, got)
+}
@@ -0,0 +1,236 @@
+package chainist
+
+import (
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Predicate reports whether a request matches a condition.
+type Predicate func(r *http.Request) bool
+
+/*
+If returns a middleware which applies the given middleware only when the predicate matches the request.
+Otherwise the request is passed to the succeeding handler directly.
+If the predicate or the middleware is nil, the middleware is returned as it is.
+
+    // skip authentication for health checks
+    m := chainist.If(chainist.Not(chainist.PathPrefix("/healthz")), authHandler)
+*/
+func If(pred Predicate, m Middleware) Middleware {
+	if pred == nil || m == nil {
+		return m
+	}
+	return func(next http.Handler) http.Handler {
+		h := m(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pred(r) {
+				if h != nil {
+					h.ServeHTTP(w, r)
+				}
+				return
+			}
+			if next != nil {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+/*
+Unless returns a middleware which applies the given middleware only when the predicate does not match the request.
+This is the same as If(Not(pred), m).
+
+    // do not compress streaming responses
+    m := chainist.Unless(chainist.PathPrefix("/stream"), gzipHandler)
+*/
+func Unless(pred Predicate, m Middleware) Middleware {
+	if pred == nil {
+		return m
+	}
+	return If(Not(pred), m)
+}
+
+/*
+AppendIf appends middleware which is applied only when the predicate matches the request.
+If nil is given as the predicate or the middleware, then the chain will be returned as it is.
+The chain is described with the name of the given middleware.
+
+    chain := chainist.NewChain()
+    chain.AppendIf(chainist.Method(http.MethodPost, http.MethodPut), csrfHandler)
+*/
+func (c *Chain) AppendIf(pred Predicate, m Middleware) *Chain {
+	if pred == nil || m == nil {
+		return c
+	}
+	return c.add(If(pred, m), entry{kind: KindMiddleware, fn: m})
+}
+
+/*
+AppendUnless appends middleware which is applied only when the predicate does not match the request.
+If nil is given as the predicate or the middleware, then the chain will be returned as it is.
+
+    chain := chainist.NewChain()
+    chain.AppendUnless(chainist.PathPrefix("/healthz"), authHandler)
+*/
+func (c *Chain) AppendUnless(pred Predicate, m Middleware) *Chain {
+	if pred == nil || m == nil {
+		return c
+	}
+	return c.add(Unless(pred, m), entry{kind: KindMiddleware, fn: m})
+}
+
+// Not returns a predicate which negates the given predicate.
+func Not(pred Predicate) Predicate {
+	return func(r *http.Request) bool {
+		return !pred(r)
+	}
+}
+
+// And returns a predicate which matches when all of the given predicates match.
+// This matches any requests if no predicates are given.
+func And(preds ...Predicate) Predicate {
+	return func(r *http.Request) bool {
+		for _, p := range preds {
+			if p != nil && !p(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a predicate which matches when any of the given predicates matches.
+// This matches no requests if no predicates are given.
+func Or(preds ...Predicate) Predicate {
+	return func(r *http.Request) bool {
+		for _, p := range preds {
+			if p != nil && p(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathPrefix returns a predicate which matches when the URL path has any of the given prefixes.
+func PathPrefix(prefixes ...string) Predicate {
+	return func(r *http.Request) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(r.URL.Path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathGlob returns a predicate which matches when the URL path matches any of the given patterns.
+// The syntax of patterns is the same as path.Match, e.g. "/static/*.css".
+// Malformed patterns never match.
+func PathGlob(patterns ...string) Predicate {
+	return func(r *http.Request) bool {
+		for _, p := range patterns {
+			if ok, err := path.Match(p, r.URL.Path); err == nil && ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathRegexp returns a predicate which matches when the URL path matches the regular expression.
+func PathRegexp(re *regexp.Regexp) Predicate {
+	return func(r *http.Request) bool {
+		return re.MatchString(r.URL.Path)
+	}
+}
+
+// Method returns a predicate which matches when the request method is any of the given methods.
+// Methods are compared case-sensitively as defined in RFC 9110.
+func Method(methods ...string) Predicate {
+	return func(r *http.Request) bool {
+		for _, m := range methods {
+			if r.Method == m {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Host returns a predicate which matches when the host of the request is any of the given hosts.
+// The port is ignored and the hosts are compared case-insensitively.
+// Hosts starting with "*." match any subdomains, e.g. "*.example.com" matches "api.example.com".
+func Host(hosts ...string) Predicate {
+	return func(r *http.Request) bool {
+		h := r.Host
+		if host, _, err := net.SplitHostPort(h); err == nil {
+			h = host
+		}
+		h = strings.ToLower(h)
+		for _, host := range hosts {
+			host = strings.ToLower(host)
+			if strings.HasPrefix(host, "*.") {
+				if strings.HasSuffix(h, host[1:]) && len(h) > len(host)-1 {
+					return true
+				}
+				continue
+			}
+			if h == host {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasHeader returns a predicate which matches when the request has the header.
+// If values are given, this matches only when the header has any of the values.
+func HasHeader(name string, values ...string) Predicate {
+	return func(r *http.Request) bool {
+		vs := r.Header.Values(name)
+		if len(vs) == 0 {
+			return false
+		}
+		if len(values) == 0 {
+			return true
+		}
+		for _, v := range vs {
+			for _, value := range values {
+				if v == value {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// ContentType returns a predicate which matches when the media type of the request body
+// is any of the given types. Parameters such as charset are ignored.
+// Types ending with "/*" match any subtypes, e.g. "text/*" matches "text/plain".
+func ContentType(types ...string) Predicate {
+	return func(r *http.Request) bool {
+		mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+		for _, t := range types {
+			t = strings.ToLower(t)
+			if strings.HasSuffix(t, "/*") {
+				if strings.HasPrefix(mt, t[:len(t)-1]) {
+					return true
+				}
+				continue
+			}
+			if mt == t {
+				return true
+			}
+		}
+		return false
+	}
+}
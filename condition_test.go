@@ -0,0 +1,183 @@
+package chainist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveCondition(m Middleware, r *http.Request) string {
+	w := httptest.NewRecorder()
+	m(http.HandlerFunc(handlerFunc2)).ServeHTTP(w, r)
+	return w.Body.String()
+}
+
+func TestIf(t *testing.T) {
+	m := writeMiddleware("m")
+	{
+		assert.Nil(t, If(PathPrefix("/"), nil))
+		assert.Equal(t, funcPointer(m), funcPointer(If(nil, m)))
+	}
+	{
+		c := If(PathPrefix("/api"), m)
+		assert.Equal(t, "mf2", serveCondition(c, httptest.NewRequest(http.MethodGet, "/api/users", nil)))
+		assert.Equal(t, "f2", serveCondition(c, httptest.NewRequest(http.MethodGet, "/healthz", nil)))
+	}
+	{
+		// nil next handler
+		c := If(PathPrefix("/api"), m)
+		w := httptest.NewRecorder()
+		c(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Equal(t, "", w.Body.String())
+	}
+}
+
+func TestUnless(t *testing.T) {
+	m := writeMiddleware("m")
+	{
+		assert.Equal(t, funcPointer(m), funcPointer(Unless(nil, m)))
+	}
+	{
+		c := Unless(PathPrefix("/healthz"), m)
+		assert.Equal(t, "mf2", serveCondition(c, httptest.NewRequest(http.MethodGet, "/api/users", nil)))
+		assert.Equal(t, "f2", serveCondition(c, httptest.NewRequest(http.MethodGet, "/healthz", nil)))
+	}
+}
+
+func TestAppendIf(t *testing.T) {
+	{
+		c := NewChain()
+		c.AppendIf(nil, writeMiddleware("m"))
+		c.AppendIf(PathPrefix("/"), nil)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c := NewChain()
+		c.AppendIf(PathPrefix("/api"), plainMiddleware)
+		assert.Equal(t, 1, len(c.Middleware))
+		assert.Equal(t, "github.com/t-katsumura/chainist.plainMiddleware", c.Describe().Entries[0].Name)
+		assert.Equal(t, KindMiddleware, c.Describe().Entries[0].Kind)
+	}
+	{
+		c := NewChain().AppendIf(Method(http.MethodPost), writeMiddleware("m"))
+		h := c.ChainFunc(handlerFunc1)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, "mf1", w.Body.String())
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "f1", w.Body.String())
+	}
+}
+
+func TestAppendUnless(t *testing.T) {
+	{
+		c := NewChain()
+		c.AppendUnless(nil, writeMiddleware("m"))
+		c.AppendUnless(PathPrefix("/"), nil)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c := NewChain().AppendUnless(PathPrefix("/healthz"), writeMiddleware("m"))
+		h := c.ChainFunc(handlerFunc1)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Equal(t, "f1", w.Body.String())
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "mf1", w.Body.String())
+	}
+}
+
+func TestLogicalPredicates(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api", nil)
+	yes := PathPrefix("/api")
+	no := PathPrefix("/web")
+	{
+		assert.False(t, Not(yes)(r))
+		assert.True(t, Not(no)(r))
+	}
+	{
+		assert.True(t, And()(r))
+		assert.True(t, And(yes, nil)(r))
+		assert.False(t, And(yes, no)(r))
+	}
+	{
+		assert.False(t, Or()(r))
+		assert.True(t, Or(no, yes)(r))
+		assert.False(t, Or(no, nil)(r))
+	}
+}
+
+func TestPathPredicates(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/static/css/main.css", nil)
+	{
+		assert.True(t, PathPrefix("/api", "/static")(r))
+		assert.False(t, PathPrefix("/api")(r))
+		assert.False(t, PathPrefix()(r))
+	}
+	{
+		assert.True(t, PathGlob("/static/*/*.css")(r))
+		assert.False(t, PathGlob("/static/*.css")(r))
+		assert.False(t, PathGlob("[")(r))
+	}
+	{
+		assert.True(t, PathRegexp(regexp.MustCompile(`\.css$`))(r))
+		assert.False(t, PathRegexp(regexp.MustCompile(`^/api/`))(r))
+	}
+}
+
+func TestMethod(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.True(t, Method(http.MethodGet, http.MethodPost)(r))
+	assert.False(t, Method(http.MethodGet)(r))
+	assert.False(t, Method("post")(r))
+}
+
+func TestHost(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	{
+		r.Host = "Example.com:8080"
+		assert.True(t, Host("example.com")(r))
+		assert.False(t, Host("api.example.com")(r))
+		assert.False(t, Host("*.example.com")(r))
+	}
+	{
+		r.Host = "api.example.com"
+		assert.True(t, Host("*.example.com")(r))
+		assert.False(t, Host("example.com")(r))
+	}
+	{
+		r.Host = "api.example.org"
+		assert.False(t, Host("*.example.com")(r))
+	}
+}
+
+func TestHasHeader(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add("Upgrade", "websocket")
+	assert.True(t, HasHeader("Upgrade")(r))
+	assert.True(t, HasHeader("upgrade", "h2c", "websocket")(r))
+	assert.False(t, HasHeader("Upgrade", "h2c")(r))
+	assert.False(t, HasHeader("Authorization")(r))
+}
+
+func TestContentType(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	{
+		assert.False(t, ContentType("application/json")(r))
+	}
+	{
+		r.Header.Set("Content-Type", "Application/JSON; charset=utf-8")
+		assert.True(t, ContentType("application/json")(r))
+		assert.True(t, ContentType("text/plain", "application/*")(r))
+		assert.False(t, ContentType("text/*")(r))
+	}
+	{
+		r.Header.Set("Content-Type", "invalid;;")
+		assert.False(t, ContentType("invalid")(r))
+	}
+}
@@ -0,0 +1,335 @@
+package chainist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Factory creates middleware from its configuration.
+The configuration is the "config" member of the middleware in chain configurations,
+which is nil if it is not given.
+Numbers in the configuration are float64 for both JSON and YAML.
+
+    func rateLimit(cfg map[string]any) (chainist.Middleware, error) {
+        rps, ok := cfg["rps"].(float64)
+        if !ok {
+            return nil, errors.New("rps is required")
+        }
+        return newRateLimiter(rps).Middleware, nil
+    }
+*/
+type Factory func(cfg map[string]any) (Middleware, error)
+
+/*
+Registry holds middleware factories by name
+and builds chains from configurations which refer to the names.
+The zero value is not usable. Use NewRegistry() instead.
+
+    registry := chainist.NewRegistry()
+    registry.Register("ratelimit", rateLimit)
+    chain, err := registry.LoadYAML(data)
+*/
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// DefaultRegistry is the registry used by Register(), LoadJSON() and LoadYAML().
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: map[string]Factory{}}
+}
+
+/*
+Register registers the middleware factory with the name.
+This panics if the name is empty, the factory is nil or the name is already registered.
+
+    registry.Register("ratelimit", func(cfg map[string]any) (chainist.Middleware, error) {
+        return newRateLimiter(cfg).Middleware, nil
+    })
+*/
+func (r *Registry) Register(name string, f Factory) {
+	if name == "" {
+		panic("chainist: register middleware with empty name")
+	}
+	if f == nil {
+		panic(fmt.Sprintf("chainist: register nil factory for middleware %q", name))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.factories[name]; ok {
+		panic(fmt.Sprintf("chainist: middleware %q is already registered", name))
+	}
+	r.factories[name] = f
+}
+
+// Names returns the sorted names of the registered middleware.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+LoadJSON builds a chain from the JSON configuration.
+Unknown members are rejected so that typos do not silently disable middleware.
+See ChainConfig for the format.
+
+    {
+      "label": "api",
+      "middleware": [
+        {"name": "logging"},
+        {"name": "ratelimit", "config": {"rps": 10}, "when": {"path_prefix": ["/api"]}},
+        {"name": "debug", "disabled": true}
+      ]
+    }
+*/
+func (r *Registry) LoadJSON(data []byte) (*Chain, error) {
+	var cfg ChainConfig
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return r.Build(&cfg)
+}
+
+/*
+LoadYAML builds a chain from the YAML configuration.
+The YAML configuration has the same structure as the JSON one.
+See ChainConfig for the format.
+
+    label: api
+    middleware:
+      - name: logging
+      - name: ratelimit
+        config:
+          rps: 10
+        when:
+          path_prefix: [/api]
+      - name: debug
+        disabled: true
+*/
+func (r *Registry) LoadYAML(data []byte) (*Chain, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	// YAML is converted into JSON so that both formats are decoded in the same way.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return r.LoadJSON(b)
+}
+
+/*
+Build builds a chain from the configuration.
+Middleware is added in the order of the configuration, and disabled middleware is skipped.
+Ordering constraints which refer to disabled middleware are ignored.
+Errors are returned when the configuration refers to unregistered middleware,
+factories fail, or the ordering constraints can not be satisfied.
+*/
+func (r *Registry) Build(cfg *ChainConfig) (*Chain, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("%w: nil config", ErrInvalidConfig)
+	}
+	disabled := map[string]bool{}
+	seen := map[string]bool{}
+	for i, mc := range cfg.Middleware {
+		id := mc.id()
+		if id == "" {
+			return nil, fmt.Errorf("%w: middleware at position %d has no name", ErrInvalidConfig, i)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%w: middleware %q is configured more than once", ErrInvalidConfig, id)
+		}
+		seen[id] = true
+		disabled[id] = mc.Disabled
+	}
+	enabled := func(names []string) []string {
+		var ns []string
+		for _, n := range names {
+			if !disabled[n] {
+				ns = append(ns, n)
+			}
+		}
+		return ns
+	}
+
+	c := NewChain().SetLabel(cfg.Label)
+	for _, mc := range cfg.Middleware {
+		if mc.Disabled {
+			continue
+		}
+		id := mc.id()
+		f := r.factory(mc.Name)
+		if f == nil {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownMiddleware, mc.Name)
+		}
+		m, err := f(mc.Config)
+		if err != nil {
+			return nil, fmt.Errorf("chainist: middleware %q: %w", id, err)
+		}
+		if m == nil {
+			return nil, fmt.Errorf("%w: factory of %q returned nil", ErrNilMiddleware, id)
+		}
+		when, err := mc.When.predicate()
+		if err != nil {
+			return nil, fmt.Errorf("%w: middleware %q: %v", ErrInvalidConfig, id, err)
+		}
+		unless, err := mc.Unless.predicate()
+		if err != nil {
+			return nil, fmt.Errorf("%w: middleware %q: %v", ErrInvalidConfig, id, err)
+		}
+		e := entry{name: id}
+		if when != nil || unless != nil {
+			e = entry{name: id, kind: KindMiddleware, fn: m}
+			m = Unless(unless, If(when, m))
+		}
+		c.add(m, e)
+		c.After(id, enabled(mc.After)...)
+		c.Before(id, enabled(mc.Before)...)
+	}
+	if _, err := c.Resolve(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// factory returns the factory registered with the name.
+// nil is returned if the name is not registered.
+func (r *Registry) factory(name string) Factory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.factories[name]
+}
+
+// Register registers the middleware factory with the name to the DefaultRegistry.
+// See Registry.Register().
+func Register(name string, f Factory) {
+	DefaultRegistry.Register(name, f)
+}
+
+// LoadJSON builds a chain from the JSON configuration with the DefaultRegistry.
+// See Registry.LoadJSON().
+func LoadJSON(data []byte) (*Chain, error) {
+	return DefaultRegistry.LoadJSON(data)
+}
+
+// LoadYAML builds a chain from the YAML configuration with the DefaultRegistry.
+// See Registry.LoadYAML().
+func LoadYAML(data []byte) (*Chain, error) {
+	return DefaultRegistry.LoadYAML(data)
+}
+
+// ChainConfig is the configuration of a chain.
+type ChainConfig struct {
+	// Label is the label of the chain.
+	Label string `json:"label"`
+
+	// Middleware is the list of middleware in the order of the chain.
+	Middleware []MiddlewareConfig `json:"middleware"`
+}
+
+// MiddlewareConfig is the configuration of a middleware in a chain.
+type MiddlewareConfig struct {
+	// Name is the name of the registered middleware factory.
+	Name string `json:"name"`
+
+	// ID is the name of the middleware in the chain.
+	// Name is used if it is empty.
+	// This is required to use the same middleware more than once in a chain.
+	ID string `json:"id"`
+
+	// Config is passed to the middleware factory.
+	Config map[string]any `json:"config"`
+
+	// Disabled disables the middleware.
+	Disabled bool `json:"disabled"`
+
+	// When applies the middleware only to the requests matching the condition.
+	When *Condition `json:"when"`
+
+	// Unless applies the middleware only to the requests not matching the condition.
+	Unless *Condition `json:"unless"`
+
+	// After is the names of middleware which must run before the middleware.
+	After []string `json:"after"`
+
+	// Before is the names of middleware which must run after the middleware.
+	Before []string `json:"before"`
+}
+
+// id returns the name of the middleware in the chain.
+func (mc *MiddlewareConfig) id() string {
+	if mc.ID != "" {
+		return mc.ID
+	}
+	return mc.Name
+}
+
+// Condition is the condition of requests in chain configurations.
+// A request matches the condition when it matches all the given members.
+// See the predicates of the same names for the details of each member.
+type Condition struct {
+	PathPrefix  []string            `json:"path_prefix"`
+	PathGlob    []string            `json:"path_glob"`
+	PathRegexp  string              `json:"path_regexp"`
+	Method      []string            `json:"method"`
+	Host        []string            `json:"host"`
+	Header      map[string][]string `json:"header"`
+	ContentType []string            `json:"content_type"`
+}
+
+// predicate returns the predicate of the condition.
+// nil is returned if the condition is nil or empty.
+func (c *Condition) predicate() (Predicate, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var preds []Predicate
+	if len(c.PathPrefix) > 0 {
+		preds = append(preds, PathPrefix(c.PathPrefix...))
+	}
+	if len(c.PathGlob) > 0 {
+		preds = append(preds, PathGlob(c.PathGlob...))
+	}
+	if c.PathRegexp != "" {
+		re, err := regexp.Compile(c.PathRegexp)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, PathRegexp(re))
+	}
+	if len(c.Method) > 0 {
+		preds = append(preds, Method(c.Method...))
+	}
+	if len(c.Host) > 0 {
+		preds = append(preds, Host(c.Host...))
+	}
+	for name, values := range c.Header {
+		preds = append(preds, HasHeader(name, values...))
+	}
+	if len(c.ContentType) > 0 {
+		preds = append(preds, ContentType(c.ContentType...))
+	}
+	if len(preds) == 0 {
+		return nil, nil
+	}
+	return And(preds...), nil
+}
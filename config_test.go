@@ -0,0 +1,208 @@
+package chainist
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRegistry() *Registry {
+	r := NewRegistry()
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		r.Register(name, func(cfg map[string]any) (Middleware, error) {
+			return writeMiddleware(name), nil
+		})
+	}
+	r.Register("write", func(cfg map[string]any) (Middleware, error) {
+		s, ok := cfg["text"].(string)
+		if !ok {
+			return nil, errors.New("text is required")
+		}
+		if n, ok := cfg["repeat"].(float64); ok {
+			s = fmt.Sprintf("%s*%d", s, int(n))
+		}
+		return writeMiddleware(s), nil
+	})
+	r.Register("nil", func(cfg map[string]any) (Middleware, error) {
+		return nil, nil
+	})
+	return r
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	f := func(cfg map[string]any) (Middleware, error) { return nil, nil }
+	r.Register("b", f)
+	r.Register("a", f)
+	assert.Equal(t, []string{"a", "b"}, r.Names())
+	assert.Panics(t, func() { r.Register("a", f) })
+	assert.Panics(t, func() { r.Register("", f) })
+	assert.Panics(t, func() { r.Register("c", nil) })
+}
+
+func TestRegistryLoadJSON(t *testing.T) {
+	r := testRegistry()
+	{
+		c, err := r.LoadJSON([]byte(`{
+			"label": "api",
+			"middleware": [
+				{"name": "a"},
+				{"name": "write", "config": {"text": "w", "repeat": 2}},
+				{"name": "b", "disabled": true},
+				{"name": "c"}
+			]
+		}`))
+		assert.Nil(t, err)
+		assert.Equal(t, "api", c.label)
+		assert.True(t, c.Has("write"))
+		assert.False(t, c.Has("b"))
+		assert.Equal(t, "aw*2cf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+	}
+	{
+		// reordering and multiple instances
+		c, err := r.LoadJSON([]byte(`{"middleware": [
+			{"name": "a", "after": ["c", "b"]},
+			{"name": "b", "disabled": true},
+			{"name": "write", "id": "w1", "config": {"text": "x"}},
+			{"name": "write", "id": "w2", "config": {"text": "y"}, "before": ["w1"]},
+			{"name": "c"}
+		]}`))
+		assert.Nil(t, err)
+		assert.Equal(t, "yxcaf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+	}
+	{
+		c, err := r.LoadJSON([]byte(`{"middleware": []}`))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, c.Len())
+	}
+	errorCases := []struct {
+		json string
+		err  error
+	}{
+		{`{"middleware": [{"name": "a"}`, ErrInvalidConfig},
+		{`{"middleware": [{"name": "a", "disable": true}]}`, ErrInvalidConfig},
+		{`{"middleware": [{"config": {}}]}`, ErrInvalidConfig},
+		{`{"middleware": [{"name": "a"}, {"name": "a"}]}`, ErrInvalidConfig},
+		{`{"middleware": [{"name": "unknown"}]}`, ErrUnknownMiddleware},
+		{`{"middleware": [{"name": "nil"}]}`, ErrNilMiddleware},
+		{`{"middleware": [{"name": "a", "when": {"path_regexp": "("}}]}`, ErrInvalidConfig},
+		{`{"middleware": [{"name": "a", "after": ["x"]}]}`, ErrMissingDependency},
+		{`{"middleware": [{"name": "a", "after": ["b"]}, {"name": "b", "after": ["a"]}]}`, ErrDependencyCycle},
+	}
+	for _, tc := range errorCases {
+		c, err := r.LoadJSON([]byte(tc.json))
+		assert.Nil(t, c, tc.json)
+		assert.ErrorIs(t, err, tc.err, tc.json)
+	}
+	{
+		// errors of factories are wrapped
+		c, err := r.LoadJSON([]byte(`{"middleware": [{"name": "write"}]}`))
+		assert.Nil(t, c)
+		assert.EqualError(t, err, `chainist: middleware "write": text is required`)
+	}
+}
+
+func TestRegistryLoadJSONCondition(t *testing.T) {
+	r := testRegistry()
+	c, err := r.LoadJSON([]byte(`{"middleware": [
+		{"name": "a", "when": {"path_prefix": ["/api"], "method": ["POST"]}},
+		{"name": "b", "unless": {"header": {"X-Debug": []}}},
+		{"name": "c", "when": {"host": ["*.example.com"], "content_type": ["application/json"]}}
+	]}`))
+	assert.Nil(t, err)
+	h := c.ChainFunc(handlerFunc1)
+	serve := func(method, target string, header map[string]string) string {
+		r := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+	assert.Equal(t, "abf1", serve(http.MethodPost, "/api/users", nil))
+	assert.Equal(t, "bf1", serve(http.MethodGet, "/api/users", nil))
+	assert.Equal(t, "f1", serve(http.MethodGet, "/", map[string]string{"X-Debug": "1"}))
+	assert.Equal(t, "bcf1", serve(http.MethodPut, "http://api.example.com/", map[string]string{"Content-Type": "application/json"}))
+	assert.Equal(t, "bf1", serve(http.MethodPut, "http://example.com/", map[string]string{"Content-Type": "application/json"}))
+}
+
+func TestRegistryLoadYAML(t *testing.T) {
+	r := testRegistry()
+	{
+		c, err := r.LoadYAML([]byte(`
+label: api
+middleware:
+  - name: c
+  - name: write
+    config:
+      text: w
+      repeat: 3
+    when:
+      path_glob: ["/users/*"]
+  - name: a
+    before: [c]
+  - name: b
+    disabled: true
+`))
+		assert.Nil(t, err)
+		assert.Equal(t, "api", c.label)
+		assert.Equal(t, "acf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+		w := httptest.NewRecorder()
+		c.ChainFunc(handlerFunc1).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		assert.Equal(t, "w*3acf1", w.Body.String())
+	}
+	{
+		c, err := r.LoadYAML([]byte("middleware: [\n"))
+		assert.Nil(t, c)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+	{
+		c, err := r.LoadYAML([]byte("middleware:\n  - name: a\n    disable: true\n"))
+		assert.Nil(t, c)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+}
+
+func TestRegistryBuild(t *testing.T) {
+	r := testRegistry()
+	{
+		c, err := r.Build(nil)
+		assert.Nil(t, c)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+	{
+		c, err := r.Build(&ChainConfig{Middleware: []MiddlewareConfig{
+			{Name: "a"},
+			{Name: "write", Config: map[string]any{"text": "w"}},
+		}})
+		assert.Nil(t, err)
+		assert.Equal(t, "awf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	// the test has its own registry so that it can run repeatedly
+	defaultRegistry := DefaultRegistry
+	DefaultRegistry = NewRegistry()
+	t.Cleanup(func() { DefaultRegistry = defaultRegistry })
+
+	Register("chainist-test", func(cfg map[string]any) (Middleware, error) {
+		return writeMiddleware("t"), nil
+	})
+	{
+		c, err := LoadJSON([]byte(`{"middleware": [{"name": "chainist-test"}]}`))
+		assert.Nil(t, err)
+		assert.Equal(t, "tf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+	}
+	{
+		c, err := LoadYAML([]byte("middleware:\n  - name: chainist-test\n"))
+		assert.Nil(t, err)
+		assert.Equal(t, "tf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+	}
+}
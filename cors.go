@@ -0,0 +1,316 @@
+package chainist
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CORSOptions is the options of CORS().
+type CORSOptions struct {
+	// AllowedOrigins is the list of origins which are allowed to access resources.
+	// "*" allows any origins and origins containing "*" allow any subdomains,
+	// e.g. "https://*.example.com" allows "https://api.example.com" but not "https://example.com".
+	// Origins are compared case-insensitively.
+	AllowedOrigins []string
+
+	// AllowedOriginPatterns is the list of regular expressions of the allowed origins.
+	AllowedOriginPatterns []*regexp.Regexp
+
+	// AllowOriginFunc reports whether the origin is allowed.
+	// This is called only when the origin is not allowed by AllowedOrigins and AllowedOriginPatterns.
+	AllowOriginFunc func(r *http.Request, origin string) bool
+
+	// AllowedMethods is the list of methods allowed for cross-origin requests.
+	// GET, HEAD and POST are used if it is empty.
+	// Methods are compared case-sensitively.
+	AllowedMethods []string
+
+	// AllowedHeaders is the list of request headers allowed for cross-origin requests.
+	// "*" allows any headers. Headers are compared case-insensitively.
+	// CORS-safelisted request headers are not checked by clients, but they must be listed
+	// when they have non-safelisted values, e.g. "Content-Type: application/json".
+	AllowedHeaders []string
+
+	// ExposedHeaders is the list of response headers exposed to clients.
+	ExposedHeaders []string
+
+	// AllowCredentials allows requests with credentials such as cookies.
+	// The origin of the request is responded instead of "*" when credentials are allowed.
+	AllowCredentials bool
+
+	// MaxAge is the number of seconds which the results of preflight requests can be cached.
+	// The header is not responded if it is 0, and "0" is responded if it is negative to disable caching.
+	MaxAge int
+
+	// AllowPrivateNetwork allows requests from public networks to private networks.
+	// See the Private Network Access specification.
+	AllowPrivateNetwork bool
+
+	// PreflightStatus is the status code of responses to preflight requests.
+	// 204 No Content is used if it is 0.
+	PreflightStatus int
+}
+
+/*
+CORS returns a middleware which handles cross-origin resource sharing.
+Preflight requests are responded by the middleware without invoking succeeding middleware and handlers,
+so this should be placed at the first of a chain.
+For other requests, the CORS headers are added to the response when the origin is allowed.
+If nil is given as the options, any origins are allowed with the default methods.
+
+    chain := chainist.NewChain(chainist.CORS(&chainist.CORSOptions{
+        AllowedOrigins:   []string{"https://example.com", "https://*.example.com"},
+        AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+        AllowedHeaders:   []string{"Authorization", "Content-Type"},
+        ExposedHeaders:   []string{"X-Request-ID"},
+        AllowCredentials: true,
+        MaxAge:           600,
+    }))
+*/
+func CORS(opts *CORSOptions) Middleware {
+	if opts == nil {
+		opts = &CORSOptions{AllowedOrigins: []string{"*"}}
+	}
+	c := newCORS(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isPreflight(r) {
+				c.preflight(w, r)
+				return
+			}
+			c.actual(w, r)
+			if next != nil {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// isPreflight reports whether the request is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// cors is the compiled options of CORS().
+type cors struct {
+	anyOrigin       bool
+	origins         map[string]bool
+	wildcards       [][2]string
+	patterns        []*regexp.Regexp
+	originFunc      func(r *http.Request, origin string) bool
+	methods         map[string]bool
+	anyHeader       bool
+	headers         map[string]bool
+	exposedHeaders  string
+	credentials     bool
+	maxAge          string
+	privateNetwork  bool
+	preflightStatus int
+}
+
+func newCORS(opts *CORSOptions) *cors {
+	c := &cors{
+		origins:         map[string]bool{},
+		patterns:        opts.AllowedOriginPatterns,
+		originFunc:      opts.AllowOriginFunc,
+		methods:         map[string]bool{},
+		headers:         map[string]bool{},
+		exposedHeaders:  strings.Join(opts.ExposedHeaders, ", "),
+		credentials:     opts.AllowCredentials,
+		privateNetwork:  opts.AllowPrivateNetwork,
+		preflightStatus: opts.PreflightStatus,
+	}
+	for _, o := range opts.AllowedOrigins {
+		o = strings.ToLower(o)
+		switch i := strings.IndexByte(o, '*'); {
+		case o == "*":
+			c.anyOrigin = true
+		case i >= 0:
+			c.wildcards = append(c.wildcards, [2]string{o[:i], o[i+1:]})
+		default:
+			c.origins[o] = true
+		}
+	}
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	for _, m := range methods {
+		c.methods[m] = true
+	}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			c.anyHeader = true
+		}
+		c.headers[strings.ToLower(h)] = true
+	}
+	switch {
+	case opts.MaxAge > 0:
+		c.maxAge = strconv.Itoa(opts.MaxAge)
+	case opts.MaxAge < 0:
+		c.maxAge = "0"
+	}
+	if c.preflightStatus == 0 {
+		c.preflightStatus = http.StatusNoContent
+	}
+	return c
+}
+
+// preflight responds to the preflight request.
+// The CORS headers are not responded if the request is not allowed,
+// so that clients do not send the actual request.
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	addVary(h, "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers")
+	if c.privateNetwork {
+		addVary(h, "Access-Control-Request-Private-Network")
+	}
+	defer w.WriteHeader(c.preflightStatus)
+
+	origin := r.Header.Get("Origin")
+	if !c.allowOrigin(r, origin) {
+		return
+	}
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !c.methods[method] && !safelistedMethods[method] {
+		return
+	}
+	headers := requestHeaders(r)
+	for _, name := range headers {
+		if !c.anyHeader && !c.headers[name] {
+			return
+		}
+	}
+	if r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		if !c.privateNetwork {
+			return
+		}
+		h.Set("Access-Control-Allow-Private-Network", "true")
+	}
+
+	c.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", method)
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+}
+
+// actual adds the CORS headers to the response of the actual request.
+func (c *cors) actual(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	if c.varyByOrigin() {
+		addVary(h, "Origin")
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.allowOrigin(r, origin) {
+		return
+	}
+	c.setOrigin(h, origin)
+	if c.exposedHeaders != "" {
+		h.Set("Access-Control-Expose-Headers", c.exposedHeaders)
+	}
+}
+
+// setOrigin sets the Access-Control-Allow-Origin header and the credentials header.
+func (c *cors) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin && !c.credentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// varyByOrigin reports whether responses vary by the Origin header.
+func (c *cors) varyByOrigin() bool {
+	return !c.anyOrigin || c.credentials
+}
+
+// allowOrigin reports whether the origin is allowed.
+func (c *cors) allowOrigin(r *http.Request, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.anyOrigin {
+		return true
+	}
+	o := strings.ToLower(origin)
+	if c.origins[o] {
+		return true
+	}
+	for _, wc := range c.wildcards {
+		prefix, suffix := wc[0], wc[1]
+		if len(o) > len(prefix)+len(suffix) && strings.HasPrefix(o, prefix) && strings.HasSuffix(o, suffix) &&
+			validSubdomain(o[len(prefix):len(o)-len(suffix)]) {
+			return true
+		}
+	}
+	for _, p := range c.patterns {
+		if p.MatchString(origin) {
+			return true
+		}
+	}
+	return c.originFunc != nil && c.originFunc(r, origin)
+}
+
+// validSubdomain reports whether s consists of the characters of host names,
+// so that wildcards do not match other parts of origins such as schemes and ports.
+func validSubdomain(s string) bool {
+	if s == "" || s[0] == '.' || strings.Contains(s, "..") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// requestHeaders returns the lower-cased header names of Access-Control-Request-Headers.
+func requestHeaders(r *http.Request) []string {
+	var names []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// addVary adds the header names to the Vary header without duplicates.
+func addVary(h http.Header, names ...string) {
+	existing := map[string]bool{}
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			existing[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+	if existing["*"] {
+		return
+	}
+	for _, name := range names {
+		if !existing[strings.ToLower(name)] {
+			h.Add("Vary", name)
+		}
+	}
+}
+
+// safelistedMethods is the CORS-safelisted methods which are always allowed.
+var safelistedMethods = map[string]bool{
+	http.MethodGet:  true,
+	http.MethodHead: true,
+	http.MethodPost: true,
+}
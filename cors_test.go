@@ -0,0 +1,247 @@
+package chainist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveCORS serves the request with the headers through the chain with the CORS middleware.
+// The body of the response is "f1" only when the handler function was invoked.
+func serveCORS(opts *CORSOptions, method string, header map[string]string) *httptest.ResponseRecorder {
+	h := NewChain(CORS(opts)).ChainFunc(handlerFunc1)
+	r := httptest.NewRequest(method, "/", nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// preflight returns the headers of the preflight request.
+func preflight(origin, method, headers string) map[string]string {
+	h := map[string]string{
+		"Origin":                        origin,
+		"Access-Control-Request-Method": method,
+	}
+	if headers != "" {
+		h["Access-Control-Request-Headers"] = headers
+	}
+	return h
+}
+
+func TestCORSActual(t *testing.T) {
+	{
+		// any origins by default
+		w := serveCORS(nil, http.MethodGet, map[string]string{"Origin": "https://example.com"})
+		assert.Equal(t, "f1", w.Body.String())
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Nil(t, w.Header().Values("Vary"))
+	}
+	{
+		// same-origin or non-browser requests
+		w := serveCORS(nil, http.MethodGet, nil)
+		assert.Equal(t, "f1", w.Body.String())
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	opts := &CORSOptions{
+		AllowedOrigins:   []string{"https://example.com"},
+		ExposedHeaders:   []string{"X-Request-ID", "X-Total-Count"},
+		AllowCredentials: true,
+	}
+	{
+		w := serveCORS(opts, http.MethodPost, map[string]string{"Origin": "https://example.com"})
+		assert.Equal(t, "f1", w.Body.String())
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "X-Request-ID, X-Total-Count", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+	}
+	{
+		// disallowed origins get no CORS headers but the handler is invoked
+		w := serveCORS(opts, http.MethodGet, map[string]string{"Origin": "https://evil.com"})
+		assert.Equal(t, "f1", w.Body.String())
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+	}
+	{
+		// Vary is added even without Origin so that caches do not mix responses
+		w := serveCORS(opts, http.MethodGet, nil)
+		assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+	}
+	{
+		// credentials with any origins respond the origin
+		w := serveCORS(&CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}, http.MethodGet,
+			map[string]string{"Origin": "https://example.com"})
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+	}
+	{
+		// OPTIONS without Access-Control-Request-Method is not a preflight request
+		w := serveCORS(opts, http.MethodOptions, map[string]string{"Origin": "https://example.com"})
+		assert.Equal(t, "f1", w.Body.String())
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	{
+		// nor without Origin
+		w := serveCORS(opts, http.MethodOptions, map[string]string{"Access-Control-Request-Method": "GET"})
+		assert.Equal(t, "f1", w.Body.String())
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	opts := &CORSOptions{
+		AllowedOrigins:        []string{"https://Example.com", "https://*.example.com", "http://*.local.test:8080", "null"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://pr-\d+\.preview\.test$`)},
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			return origin == "https://partner.test"
+		},
+	}
+	testCases := map[string]bool{
+		"https://example.com":           true,
+		"HTTPS://EXAMPLE.COM":           true,
+		"https://api.example.com":       true,
+		"https://a.b.example.com":       true,
+		"http://app.local.test:8080":    true,
+		"null":                          true,
+		"https://pr-12.preview.test":    true,
+		"https://partner.test":          true,
+		"http://example.com":            false,
+		"https://example.com:8443":      false,
+		"https://.example.com":          false,
+		"https://..example.com":         false,
+		"https://evilexample.com":       false,
+		"https://example.com.evil.com":  false,
+		"https://evil.com/.example.com": false,
+		"https://api.example.com:8443":  false,
+		"http://app.local.test":         false,
+		"https://pr-x.preview.test":     false,
+		"https://partner.test.evil":     false,
+	}
+	for origin, allowed := range testCases {
+		w := serveCORS(opts, http.MethodGet, map[string]string{"Origin": origin})
+		want := ""
+		if allowed {
+			want = origin
+		}
+		assert.Equal(t, want, w.Header().Get("Access-Control-Allow-Origin"), origin)
+
+		w = serveCORS(opts, http.MethodOptions, preflight(origin, http.MethodGet, ""))
+		assert.Equal(t, want, w.Header().Get("Access-Control-Allow-Origin"), origin)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	opts := &CORSOptions{
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Authorization", "content-type", "X-Requested-With"},
+		MaxAge:         600,
+	}
+	{
+		w := serveCORS(opts, http.MethodOptions, preflight("https://example.com", http.MethodPut, "Content-Type, authorization"))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "", w.Body.String()) // the handler is not invoked
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "PUT", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type, authorization", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+	}
+	{
+		// safelisted methods are always allowed
+		w := serveCORS(opts, http.MethodOptions, preflight("https://example.com", http.MethodPost, ""))
+		assert.Equal(t, "POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Headers"))
+	}
+	rejected := []map[string]string{
+		preflight("https://evil.com", http.MethodPut, ""),
+		preflight("https://example.com", http.MethodPatch, ""),
+		preflight("https://example.com", "put", ""), // methods are case-sensitive
+		preflight("https://example.com", http.MethodPut, "Authorization, X-Custom"),
+	}
+	for _, header := range rejected {
+		w := serveCORS(opts, http.MethodOptions, header)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "", w.Body.String())
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"), header)
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Methods"), header)
+		assert.Equal(t, 3, len(w.Header().Values("Vary")))
+	}
+	{
+		// any headers and status
+		opts := &CORSOptions{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}, PreflightStatus: http.StatusOK, MaxAge: -1}
+		w := serveCORS(opts, http.MethodOptions, preflight("https://example.com", http.MethodGet, "x-a,x-b , X-C"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "x-a, x-b, x-c", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "0", w.Header().Get("Access-Control-Max-Age"))
+	}
+	{
+		// the default options
+		w := serveCORS(nil, http.MethodOptions, preflight("https://example.com", http.MethodDelete, ""))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+		w = serveCORS(nil, http.MethodOptions, preflight("https://example.com", http.MethodGet, "Content-Type"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+		w = serveCORS(nil, http.MethodOptions, preflight("https://example.com", http.MethodHead, ""))
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Max-Age"))
+	}
+}
+
+func TestCORSPrivateNetwork(t *testing.T) {
+	header := preflight("https://example.com", http.MethodGet, "")
+	header["Access-Control-Request-Private-Network"] = "true"
+	{
+		w := serveCORS(&CORSOptions{AllowedOrigins: []string{"*"}}, http.MethodOptions, header)
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Private-Network"))
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	{
+		w := serveCORS(&CORSOptions{AllowedOrigins: []string{"*"}, AllowPrivateNetwork: true}, http.MethodOptions, header)
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Private-Network"))
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, w.Header().Values("Vary"), "Access-Control-Request-Private-Network")
+	}
+}
+
+func TestCORSInChain(t *testing.T) {
+	{
+		// preflight requests do not reach guards such as authentication
+		c := NewChain(CORS(nil)).AppendGuard(func(w http.ResponseWriter, r *http.Request) bool {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		})
+		h := c.ChainFunc(handlerFunc1)
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		for k, v := range preflight("https://example.com", http.MethodGet, "") {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+	{
+		// existing Vary headers are kept
+		c := NewChain(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Vary", "Accept-Encoding, origin")
+				next.ServeHTTP(w, r)
+			})
+		}, CORS(&CORSOptions{AllowedOrigins: []string{"https://example.com"}}))
+		w := serveRecorder(c.ChainFunc(handlerFunc1))
+		assert.Equal(t, "Accept-Encoding, origin", strings.Join(w.Header().Values("Vary"), ";"))
+	}
+	{
+		assert.Equal(t, 200, serveRecorder(CORS(nil)(nil)).Code)
+	}
+}
@@ -0,0 +1,216 @@
+package chainist
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Kind is the kind of middleware in a chain.
+type Kind int
+
+const (
+	// KindMiddleware is the plain middleware with the signature of `func(h http.Handler) http.Handler`.
+	KindMiddleware Kind = iota
+
+	// KindPreFunc is the middleware created from a pre-executable handler function.
+	KindPreFunc
+
+	// KindPostFunc is the middleware created from a post-executable handler function.
+	KindPostFunc
+
+	// KindGuard is the middleware created from a guard function.
+	KindGuard
+
+	// KindRequestFunc is the middleware created from a request function.
+	KindRequestFunc
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindMiddleware:
+		return "middleware"
+	case KindPreFunc:
+		return "pre-func"
+	case KindPostFunc:
+		return "post-func"
+	case KindGuard:
+		return "guard"
+	case KindRequestFunc:
+		return "request-func"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Entry describes a middleware in a chain.
+type Entry struct {
+	// Position is the position of the middleware in Chain.Middleware.
+	Position int
+
+	// Name is the name of the middleware.
+	// If the middleware is not named, this is derived from the symbol of the function.
+	Name string
+
+	// Named reports whether the middleware is explicitly named.
+	Named bool
+
+	// Kind is the kind of the middleware.
+	Kind Kind
+
+	// Group is the label of the joined chain which the middleware came from.
+	// This is empty for the middleware added directly to the chain.
+	Group string
+}
+
+// Description describes the structure of a chain.
+type Description struct {
+	// Entries are the middleware of the chain in the order they are invoked.
+	// If Err is not nil, they are in the order of Chain.Middleware.
+	// nil middleware is not contained.
+	Entries []Entry
+
+	// HandlerFunc is the symbol of the handler function at the edge of the chain.
+	// This is empty if the handler function is not set.
+	HandlerFunc string
+
+	// Err is the error occurred while resolving the order of middleware.
+	Err error
+}
+
+/*
+Describe returns the description of the chain.
+This is intended to be used for debugging misassembled chains.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+    chain.AppendPreFunc(handlerFunc1)
+
+    for _, e := range chain.Describe().Entries {
+        fmt.Println(e.Position, e.Name, e.Kind)
+    }
+*/
+func (c *Chain) Describe() Description {
+	d := Description{}
+	if c.HandlerFunc != nil {
+		d.HandlerFunc = funcName(c.HandlerFunc)
+	}
+
+	order, err := c.resolve()
+	if err != nil {
+		d.Err = err
+		order = order[:0]
+		for i, m := range c.Middleware {
+			if m != nil {
+				order = append(order, i)
+			}
+		}
+	}
+
+	for _, i := range order {
+		d.Entries = append(d.Entries, c.describe(i))
+	}
+	return d
+}
+
+// describe returns the description of the i-th middleware.
+func (c *Chain) describe(i int) Entry {
+	e := c.entryAt(i)
+	m := c.Middleware[i]
+	d := Entry{
+		Position: i,
+		Name:     c.displayName(i),
+		Named:    e.name != "",
+		Kind:     e.kind,
+		Group:    e.group,
+	}
+	if e.fn == nil {
+		// the middleware was not added with the methods of Chain,
+		// so the kind is derived from the symbol
+		d.Kind = kindOf(m)
+	}
+	return d
+}
+
+// displayName returns the name of the i-th middleware used in descriptions and traces.
+// If the middleware is not named, the name is derived from the symbol of the function
+// which the middleware is created from.
+func (c *Chain) displayName(i int) string {
+	e := c.entryAt(i)
+	if e.name != "" {
+		return e.name
+	}
+	if e.fn != nil {
+		return funcName(e.fn)
+	}
+	return funcName(c.Middleware[i])
+}
+
+/*
+String returns the human-readable description of the chain.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+    chain.AppendPreFunc(handlerFunc1)
+    chain.SetHandlerFunc(handlerFuncAtEdge)
+
+    // this shows
+    //   chain (2 middleware)
+    //     0: auth (middleware)
+    //     1: main.handlerFunc1 (pre-func)
+    //     handler: main.handlerFuncAtEdge
+    fmt.Println(chain)
+*/
+func (c *Chain) String() string {
+	d := c.Describe()
+	var b strings.Builder
+	fmt.Fprintf(&b, "chain (%d middleware)\n", len(d.Entries))
+	for _, e := range d.Entries {
+		fmt.Fprintf(&b, "  %d: %s (%s)\n", e.Position, e.Name, e.Kind)
+	}
+	if d.HandlerFunc != "" {
+		fmt.Fprintf(&b, "  handler: %s\n", d.HandlerFunc)
+	} else {
+		b.WriteString("  handler: <nil>\n")
+	}
+	if d.Err != nil {
+		fmt.Fprintf(&b, "  error: %s\n", d.Err)
+	}
+	return b.String()
+}
+
+// symbols of the methods which create middleware from functions
+var kindSymbols = map[string]Kind{
+	"(*HandlerFuncWrapper).PreMiddleware":       KindPreFunc,
+	"(*HandlerFuncWrapper).Middleware":          KindPreFunc,
+	"(*HandlerFuncWrapper).PostMiddleware":      KindPostFunc,
+	"(*HandlerFuncWrapper).FinalPostMiddleware": KindPostFunc,
+	"(*GuardFuncWrapper).Middleware":            KindGuard,
+	"(*RequestFuncWrapper).Middleware":          KindRequestFunc,
+}
+
+// kindOf derives the kind of the middleware from the symbol of the function.
+func kindOf(m Middleware) Kind {
+	name := funcName(m)
+	for sym, k := range kindSymbols {
+		if strings.HasSuffix(name, "."+sym) {
+			return k
+		}
+	}
+	return KindMiddleware
+}
+
+// funcName returns the symbol name of the function.
+// The suffix of method values "-fm" is trimmed.
+func funcName(fn any) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	return strings.TrimSuffix(f.Name(), "-fm")
+}
@@ -0,0 +1,120 @@
+package chainist
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func plainMiddleware(next http.Handler) http.Handler {
+	return next
+}
+
+func TestKindString(t *testing.T) {
+	assert.Equal(t, "middleware", KindMiddleware.String())
+	assert.Equal(t, "pre-func", KindPreFunc.String())
+	assert.Equal(t, "post-func", KindPostFunc.String())
+	assert.Equal(t, "guard", KindGuard.String())
+	assert.Equal(t, "request-func", KindRequestFunc.String())
+	assert.Equal(t, "Kind(99)", Kind(99).String())
+}
+
+func TestFuncName(t *testing.T) {
+	assert.Equal(t, "", funcName(nil))
+	assert.Equal(t, "", funcName(1))
+	assert.Equal(t, "", funcName(http.HandlerFunc(nil)))
+	assert.Equal(t, "github.com/t-katsumura/chainist.handlerFunc1", funcName(handlerFunc1))
+	assert.Equal(t, "github.com/t-katsumura/chainist.(*HandlerFuncWrapper).PreMiddleware", funcName(handler1.PreMiddleware))
+}
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, KindMiddleware, kindOf(plainMiddleware))
+	assert.Equal(t, KindPreFunc, kindOf(handler1.PreMiddleware))
+	assert.Equal(t, KindPreFunc, kindOf(handler1.Middleware))
+	assert.Equal(t, KindPostFunc, kindOf(handler1.PostMiddleware))
+	assert.Equal(t, KindGuard, kindOf((&GuardFuncWrapper{}).Middleware))
+	assert.Equal(t, KindPostFunc, kindOf(handler1.FinalPostMiddleware))
+	assert.Equal(t, KindRequestFunc, kindOf((&RequestFuncWrapper{}).Middleware))
+}
+
+func TestDescribe(t *testing.T) {
+	{
+		d := NewChain().Describe()
+		assert.Equal(t, 0, len(d.Entries))
+		assert.Equal(t, "", d.HandlerFunc)
+		assert.NoError(t, d.Err)
+	}
+	{
+		c := &Chain{
+			Middleware: []Middleware{handler1.PostMiddleware, nil},
+		}
+		c.AppendNamed("auth", plainMiddleware)
+		c.AppendPreFunc(handlerFunc1)
+		c.InsertPostFunc(handlerFunc2, 0)
+		c.AppendGuard(guardFunc1)
+		c.Append(plainMiddleware)
+		c.SetHandlerFunc(handlerFunc1)
+
+		d := c.Describe()
+		assert.NoError(t, d.Err)
+		assert.Equal(t, "github.com/t-katsumura/chainist.handlerFunc1", d.HandlerFunc)
+		assert.Equal(t, []Entry{
+			{Position: 0, Name: "github.com/t-katsumura/chainist.handlerFunc2", Kind: KindPostFunc},
+			{Position: 1, Name: "github.com/t-katsumura/chainist.(*HandlerFuncWrapper).PostMiddleware", Kind: KindPostFunc},
+			{Position: 3, Name: "auth", Named: true, Kind: KindMiddleware},
+			{Position: 4, Name: "github.com/t-katsumura/chainist.handlerFunc1", Kind: KindPreFunc},
+			{Position: 5, Name: "github.com/t-katsumura/chainist.guardFunc1", Kind: KindGuard},
+			{Position: 6, Name: "github.com/t-katsumura/chainist.plainMiddleware", Kind: KindMiddleware},
+		}, d.Entries)
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("a", plainMiddleware)
+		c.AppendNamed("b", plainMiddleware)
+		c.After("a", "b")
+		d := c.Describe()
+		assert.NoError(t, d.Err)
+		assert.Equal(t, "b", d.Entries[0].Name)
+		assert.Equal(t, 1, d.Entries[0].Position)
+		assert.Equal(t, "a", d.Entries[1].Name)
+		assert.Equal(t, 0, d.Entries[1].Position)
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("a", plainMiddleware)
+		c.AppendNamed("b", plainMiddleware)
+		c.After("a", "c")
+		d := c.Describe()
+		assert.ErrorIs(t, d.Err, ErrMissingDependency)
+		assert.Equal(t, "a", d.Entries[0].Name)
+		assert.Equal(t, "b", d.Entries[1].Name)
+	}
+}
+
+func TestString(t *testing.T) {
+	{
+		assert.Equal(t, "chain (0 middleware)\n  handler: <nil>\n", NewChain().String())
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("auth", plainMiddleware)
+		c.AppendPreFunc(handlerFunc1)
+		c.SetHandlerFunc(handlerFunc2)
+		e := "chain (2 middleware)\n" +
+			"  0: auth (middleware)\n" +
+			"  1: github.com/t-katsumura/chainist.handlerFunc1 (pre-func)\n" +
+			"  handler: github.com/t-katsumura/chainist.handlerFunc2\n"
+		assert.Equal(t, e, c.String())
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("auth", plainMiddleware)
+		c.After("auth", "auth")
+		e := "chain (1 middleware)\n" +
+			"  0: auth (middleware)\n" +
+			"  handler: <nil>\n" +
+			"  error: chainist: dependency cycle: \"auth\" -> \"auth\"\n"
+		assert.Equal(t, e, c.String())
+	}
+}
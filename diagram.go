@@ -0,0 +1,189 @@
+package chainist
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+SetLabel sets the label of the chain.
+The label is used as the title of diagrams exported with DOT() and Mermaid(),
+and as the group of the middleware when the chain is joined to another chain.
+
+    auth := chainist.NewChain(handler1, handler2).SetLabel("auth")
+
+    chain := chainist.NewChain(handler3)
+    chain.Join(auth)
+
+    // handler1 and handler2 are grouped as "auth"
+    fmt.Println(chain.DOT())
+*/
+func (c *Chain) SetLabel(label string) *Chain {
+	c, done := c.mutate()
+	defer done()
+	c.label = label
+	return c
+}
+
+// diagram is the intermediate representation of a chain diagram.
+type diagram struct {
+	title   string
+	nodes   []diagramNode
+	groups  []string
+	handler string
+}
+
+type diagramNode struct {
+	id    string
+	label string
+	kind  Kind
+	group string
+}
+
+// request reports whether the node acts on the request phase.
+func (n diagramNode) request() bool {
+	return n.kind != KindPostFunc
+}
+
+// response reports whether the node acts on the response phase.
+func (n diagramNode) response() bool {
+	return n.kind == KindPostFunc || n.kind == KindMiddleware
+}
+
+func (c *Chain) diagram() diagram {
+	d := c.Describe()
+	g := diagram{title: c.label, handler: d.HandlerFunc}
+	if g.title == "" {
+		g.title = "chain"
+	}
+	if g.handler == "" {
+		g.handler = "<nil>"
+	}
+	seen := map[string]bool{}
+	for _, e := range d.Entries {
+		g.nodes = append(g.nodes, diagramNode{
+			id:    fmt.Sprintf("m%d", e.Position),
+			label: e.Name,
+			kind:  e.Kind,
+			group: e.Group,
+		})
+		if e.Group != "" && !seen[e.Group] {
+			seen[e.Group] = true
+			g.groups = append(g.groups, e.Group)
+		}
+	}
+	return g
+}
+
+// paths returns the node ids passed in the request phase and the response phase.
+// Both include the edge nodes "request", "handler" and "response".
+func (g diagram) paths() (req []string, res []string) {
+	req = append(req, "request")
+	for _, n := range g.nodes {
+		if n.request() {
+			req = append(req, n.id)
+		}
+	}
+	req = append(req, "handler")
+
+	res = append(res, "handler")
+	for i := len(g.nodes) - 1; i >= 0; i-- {
+		if g.nodes[i].response() {
+			res = append(res, g.nodes[i].id)
+		}
+	}
+	res = append(res, "response")
+	return req, res
+}
+
+/*
+DOT returns the diagram of the chain in the Graphviz DOT language.
+Solid edges are the request phase and dashed edges are the response phase.
+Pre-executable functions and guards appear only in the request phase,
+and post-executable functions appear only in the response phase.
+Middleware of joined chains is grouped into clusters.
+
+    // render with `dot -Tpng -o chain.png`
+    os.WriteFile("chain.dot", []byte(chain.DOT()), 0644)
+*/
+func (c *Chain) DOT() string {
+	g := c.diagram()
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph chain {\n")
+	fmt.Fprintf(&b, "  label=%s;\n", dotQuote(g.title))
+	fmt.Fprintf(&b, "  rankdir=LR;\n")
+	fmt.Fprintf(&b, "  node [shape=box];\n")
+	fmt.Fprintf(&b, "  request [shape=plaintext, label=\"request\"];\n")
+	fmt.Fprintf(&b, "  response [shape=plaintext, label=\"response\"];\n")
+	fmt.Fprintf(&b, "  handler [shape=ellipse, label=%s];\n", dotQuote(g.handler))
+	for _, n := range g.nodes {
+		if n.group == "" {
+			fmt.Fprintf(&b, "  %s [label=%s];\n", n.id, dotQuote(n.label+"\n("+n.kind.String()+")"))
+		}
+	}
+	for i, group := range g.groups {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(group))
+		for _, n := range g.nodes {
+			if n.group == group {
+				fmt.Fprintf(&b, "    %s [label=%s];\n", n.id, dotQuote(n.label+"\n("+n.kind.String()+")"))
+			}
+		}
+		fmt.Fprintf(&b, "  }\n")
+	}
+	req, res := g.paths()
+	fmt.Fprintf(&b, "  %s;\n", strings.Join(req, " -> "))
+	fmt.Fprintf(&b, "  %s [style=dashed];\n", strings.Join(res, " -> "))
+	fmt.Fprintf(&b, "}\n")
+	return b.String()
+}
+
+/*
+Mermaid returns the diagram of the chain as a Mermaid flowchart.
+Solid edges are the request phase and dotted edges are the response phase.
+Pre-executable functions and guards appear only in the request phase,
+and post-executable functions appear only in the response phase.
+Middleware of joined chains is grouped into subgraphs.
+
+    fmt.Println("```mermaid")
+    fmt.Print(chain.Mermaid())
+    fmt.Println("```")
+*/
+func (c *Chain) Mermaid() string {
+	g := c.diagram()
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\ntitle: %s\n---\n", mermaidQuote(g.title))
+	fmt.Fprintf(&b, "flowchart LR\n")
+	fmt.Fprintf(&b, "  request([request])\n")
+	fmt.Fprintf(&b, "  response([response])\n")
+	fmt.Fprintf(&b, "  handler((%s))\n", mermaidQuote(g.handler))
+	for _, n := range g.nodes {
+		if n.group == "" {
+			fmt.Fprintf(&b, "  %s[%s]\n", n.id, mermaidQuote(n.label+"<br/>("+n.kind.String()+")"))
+		}
+	}
+	for i, group := range g.groups {
+		fmt.Fprintf(&b, "  subgraph g%d [%s]\n", i, mermaidQuote(group))
+		for _, n := range g.nodes {
+			if n.group == group {
+				fmt.Fprintf(&b, "    %s[%s]\n", n.id, mermaidQuote(n.label+"<br/>("+n.kind.String()+")"))
+			}
+		}
+		fmt.Fprintf(&b, "  end\n")
+	}
+	req, res := g.paths()
+	fmt.Fprintf(&b, "  %s\n", strings.Join(req, " --> "))
+	fmt.Fprintf(&b, "  %s\n", strings.Join(res, " -.-> "))
+	return b.String()
+}
+
+// dotQuote quotes the string as a DOT identifier.
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// mermaidQuote quotes the string as a Mermaid label.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,98 @@
+package chainist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLabel(t *testing.T) {
+	c := NewChain().SetLabel("base")
+	assert.Equal(t, "base", c.label)
+}
+
+func TestJoinGroup(t *testing.T) {
+	sub1 := NewChain(plainMiddleware).SetLabel("auth")
+	sub2 := NewChain(plainMiddleware)
+	sub3 := NewChain().Join(NewChain(plainMiddleware).SetLabel("inner"))
+	c := NewChain(plainMiddleware).Join(sub1).Join(sub2).Join(sub3)
+
+	d := c.Describe()
+	assert.Equal(t, "", d.Entries[0].Group)
+	assert.Equal(t, "auth", d.Entries[1].Group)
+	assert.Equal(t, "joined chain 2", d.Entries[2].Group)
+	assert.Equal(t, "inner", d.Entries[3].Group)
+}
+
+func TestDOT(t *testing.T) {
+	{
+		e := "digraph chain {\n" +
+			"  label=\"chain\";\n" +
+			"  rankdir=LR;\n" +
+			"  node [shape=box];\n" +
+			"  request [shape=plaintext, label=\"request\"];\n" +
+			"  response [shape=plaintext, label=\"response\"];\n" +
+			"  handler [shape=ellipse, label=\"<nil>\"];\n" +
+			"  request -> handler;\n" +
+			"  handler -> response [style=dashed];\n" +
+			"}\n"
+		assert.Equal(t, e, NewChain().DOT())
+	}
+	{
+		c := NewChain().SetLabel(`my "chain"`)
+		c.AppendNamed("auth", plainMiddleware)
+		c.AppendPreFunc(handlerFunc1)
+		c.Join(NewChain().AppendPostFunc(handlerFunc2).SetLabel("sub"))
+		c.SetHandlerFunc(handlerFunc1)
+		e := "digraph chain {\n" +
+			"  label=\"my \\\"chain\\\"\";\n" +
+			"  rankdir=LR;\n" +
+			"  node [shape=box];\n" +
+			"  request [shape=plaintext, label=\"request\"];\n" +
+			"  response [shape=plaintext, label=\"response\"];\n" +
+			"  handler [shape=ellipse, label=\"github.com/t-katsumura/chainist.handlerFunc1\"];\n" +
+			"  m0 [label=\"auth\\n(middleware)\"];\n" +
+			"  m1 [label=\"github.com/t-katsumura/chainist.handlerFunc1\\n(pre-func)\"];\n" +
+			"  subgraph cluster_0 {\n" +
+			"    label=\"sub\";\n" +
+			"    m2 [label=\"github.com/t-katsumura/chainist.handlerFunc2\\n(post-func)\"];\n" +
+			"  }\n" +
+			"  request -> m0 -> m1 -> handler;\n" +
+			"  handler -> m2 -> m0 -> response [style=dashed];\n" +
+			"}\n"
+		assert.Equal(t, e, c.DOT())
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	{
+		e := "---\ntitle: \"chain\"\n---\n" +
+			"flowchart LR\n" +
+			"  request([request])\n" +
+			"  response([response])\n" +
+			"  handler((\"<nil>\"))\n" +
+			"  request --> handler\n" +
+			"  handler -.-> response\n"
+		assert.Equal(t, e, NewChain().Mermaid())
+	}
+	{
+		c := NewChain().SetLabel(`my "chain"`)
+		c.AppendNamed("auth", plainMiddleware)
+		c.AppendGuard(guardFunc2)
+		c.Join(NewChain().AppendPostFunc(handlerFunc2))
+		c.SetHandlerFunc(handlerFunc1)
+		e := "---\ntitle: \"my #quot;chain#quot;\"\n---\n" +
+			"flowchart LR\n" +
+			"  request([request])\n" +
+			"  response([response])\n" +
+			"  handler((\"github.com/t-katsumura/chainist.handlerFunc1\"))\n" +
+			"  m0[\"auth<br/>(middleware)\"]\n" +
+			"  m1[\"github.com/t-katsumura/chainist.guardFunc2<br/>(guard)\"]\n" +
+			"  subgraph g0 [\"joined chain 1\"]\n" +
+			"    m2[\"github.com/t-katsumura/chainist.handlerFunc2<br/>(post-func)\"]\n" +
+			"  end\n" +
+			"  request --> m0 --> m1 --> handler\n" +
+			"  handler -.-> m2 -.-> m0 -.-> response\n"
+		assert.Equal(t, e, c.Mermaid())
+	}
+}
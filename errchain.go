@@ -0,0 +1,308 @@
+package chainist
+
+import (
+	"context"
+	"net/http"
+)
+
+/*
+ErrHandler is a http handler function which returns an error.
+Returned errors are converted into responses by the ErrorHandler of ErrChain.
+
+    func getUser(w http.ResponseWriter, r *http.Request) error {
+        user, err := findUser(chainist.PathParam(r, "id"))
+        if err != nil {
+            return err
+        }
+        return json.NewEncoder(w).Encode(user)
+    }
+*/
+type ErrHandler func(w http.ResponseWriter, r *http.Request) error
+
+/*
+ErrMiddleware is the middleware for ErrHandler.
+An example of basic definition of error-returning middleware is
+
+    func YourErrMiddleware(next chainist.ErrHandler) chainist.ErrHandler {
+        return func(w http.ResponseWriter, r *http.Request) error {
+            if r.Header.Get("Authorization") == "" {
+                return chainist.NewHTTPError(http.StatusUnauthorized, nil)
+            }
+            return next(w, r)
+        }
+    }
+*/
+type ErrMiddleware = GenericMiddleware[ErrHandler]
+
+type errKey struct{}
+
+/*
+AdaptMiddleware converts the middleware into ErrMiddleware.
+Errors returned by succeeding handlers are propagated through the middleware.
+
+    chain := chainist.NewErrChain(chainist.AdaptMiddleware(handler1))
+*/
+func AdaptMiddleware(m Middleware) ErrMiddleware {
+	if m == nil {
+		return nil
+	}
+	return func(next ErrHandler) ErrHandler {
+		h := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := next(w, r)
+			if p, ok := r.Context().Value(errKey{}).(*error); ok {
+				*p = err
+			}
+		}))
+		return func(w http.ResponseWriter, r *http.Request) error {
+			if h == nil {
+				return nil
+			}
+			var err error
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errKey{}, &err)))
+			return err
+		}
+	}
+}
+
+/*
+ErrChain is the middleware chain for error-returning handlers.
+This is built on GenericChain for ErrHandler and provides the same Append/Insert/Extend/Join API as Chain.
+The handler function at the edge of the chain is set with SetHandlerFunc().
+In addition, this holds an ErrorHandler which converts returned errors into responses.
+
+    chain := chainist.NewErrChain(errMiddleware1, errMiddleware2)
+    chain.SetErrorHandler(&chainist.JSONErrorHandler{})
+
+    handler := chain.ChainFunc(getUser)
+*/
+type ErrChain struct {
+	GenericChain[ErrHandler]
+
+	// ErrorHandler converts errors returned by the chain into responses.
+	// JSONErrorHandler is used if it is nil.
+	ErrorHandler ErrorHandler
+}
+
+/*
+NewErrChain creates a new error-returning middleware chain.
+If nil is contained in the given arguments, nil is returned.
+
+    chain := chainist.NewErrChain(errMiddleware1, errMiddleware2)
+*/
+func NewErrChain(ms ...ErrMiddleware) *ErrChain {
+	g := NewGenericChain(ms...)
+	if g == nil {
+		return nil
+	}
+	return &ErrChain{GenericChain: *g}
+}
+
+/*
+Append middleware at the last of the chain.
+If nil is given, then the chain will be returned without adding it.
+
+    chain.Append(errMiddleware1).Append(errMiddleware2)
+*/
+func (c *ErrChain) Append(m ErrMiddleware) *ErrChain {
+	c.GenericChain.Append(m)
+	return c
+}
+
+/*
+AppendPreFunc appends a function which is executed before invoking succeeding middleware.
+If the function returns an error, succeeding middleware is not invoked and the error is returned.
+If nil is given, then the chain will be returned as it is.
+
+    chain.AppendPreFunc(func(w http.ResponseWriter, r *http.Request) error {
+        if r.Header.Get("Authorization") == "" {
+            return chainist.NewHTTPError(http.StatusUnauthorized, nil)
+        }
+        return nil
+    })
+*/
+func (c *ErrChain) AppendPreFunc(f ErrHandler) *ErrChain {
+	if f == nil {
+		return c
+	}
+	return c.Append(errPreMiddleware(f))
+}
+
+/*
+AppendPostFunc appends a function which is executed after invoking succeeding middleware.
+The function is executed only when succeeding middleware returned no errors.
+If nil is given, then the chain will be returned as it is.
+
+    chain.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) error {
+        return audit(r)
+    })
+*/
+func (c *ErrChain) AppendPostFunc(f ErrHandler) *ErrChain {
+	if f == nil {
+		return c
+	}
+	return c.Append(errPostMiddleware(f))
+}
+
+/*
+Insert middleware at the designated position of the chain.
+If nil is given, then the chain will be returned without inserting it.
+Positions are handled in the same way as Chain.Insert().
+
+    chain.Insert(errMiddleware1, 0)
+*/
+func (c *ErrChain) Insert(m ErrMiddleware, i int) *ErrChain {
+	c.GenericChain.Insert(m, i)
+	return c
+}
+
+/*
+Extend the chain with the given middleware.
+nil is ignored.
+
+    chain.Extend(errMiddleware1, errMiddleware2)
+*/
+func (c *ErrChain) Extend(ms ...ErrMiddleware) *ErrChain {
+	c.GenericChain.Extend(ms...)
+	return c
+}
+
+/*
+Join the middleware of the other chain at the last of the chain.
+The handler function and the error handler of the other chain are not used.
+If nil is given, then the chain will be returned as it is.
+
+    chain.Join(anotherChain)
+*/
+func (c *ErrChain) Join(o *ErrChain) *ErrChain {
+	if o == nil {
+		return c
+	}
+	c.GenericChain.Join(&o.GenericChain)
+	return c
+}
+
+/*
+SetHandlerFunc sets the handler function at the edge of the chain.
+This is the same as SetHandler().
+
+    chain.SetHandlerFunc(getUser)
+*/
+func (c *ErrChain) SetHandlerFunc(f ErrHandler) *ErrChain {
+	return c.SetHandler(f)
+}
+
+/*
+SetHandler sets the handler function at the edge of the chain.
+
+    chain.SetHandler(getUser)
+*/
+func (c *ErrChain) SetHandler(f ErrHandler) *ErrChain {
+	c.GenericChain.SetHandler(f)
+	return c
+}
+
+/*
+SetErrorHandler sets the error handler which converts errors returned by the chain into responses.
+
+    chain.SetErrorHandler(chainist.ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+        http.Error(w, err.Error(), chainist.StatusOf(err))
+    }))
+*/
+func (c *ErrChain) SetErrorHandler(h ErrorHandler) *ErrChain {
+	c.ErrorHandler = h
+	return c
+}
+
+/*
+Len gets the length of middleware chain.
+This contains the length of Middleware and the Handler Function if it's already set.
+*/
+func (c *ErrChain) Len() int {
+	length := len(c.Middleware)
+	if c.Handler != nil {
+		length += 1
+	}
+	return length
+}
+
+/*
+Clone returns a copy of the chain.
+The copy does not share the slice of middleware with the chain, so modifying one does not affect the other.
+
+    admin := base.Clone().AppendPreFunc(requireAdmin)
+*/
+func (c *ErrChain) Clone() *ErrChain {
+	n := *c
+	n.GenericChain = *c.GenericChain.Clone()
+	return &n
+}
+
+/*
+Chain returns a new http handler of the chain.
+This is the same as calling ChainFunc(nil).
+
+    http.ListenAndServe(":8080", chain.Chain())
+*/
+func (c *ErrChain) Chain() http.Handler {
+	return c.ChainFunc(nil)
+}
+
+/*
+ChainFunc returns a new http handler of the chain with a handler function.
+If the given handler function is not nil, it is used instead of the chain.Handler which is set with `SetHandlerFunc()`.
+If both are nil, the chain ends without errors after the last middleware.
+Errors returned by the chain are passed to the ErrorHandler.
+Responses are recorded, so the ErrorHandler can use ResponseInfo().
+The store of request-scoped values is installed in the same way as Chain.ChainFunc().
+
+    handler := chain.ChainFunc(getUser)
+*/
+func (c *ErrChain) ChainFunc(f ErrHandler) http.Handler {
+	if f == nil {
+		f = c.Handler
+	}
+	if f == nil {
+		f = func(w http.ResponseWriter, r *http.Request) error {
+			return nil
+		}
+	}
+	f = c.Then(f)
+	eh := c.ErrorHandler
+	if eh == nil {
+		eh = &JSONErrorHandler{}
+	}
+	return valuesMiddleware(recordMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := f(w, r); err != nil {
+			eh.HandleError(w, r, err)
+		}
+	})))
+}
+
+// errPreMiddleware returns the middleware which executes f before next.
+func errPreMiddleware(f ErrHandler) ErrMiddleware {
+	return func(next ErrHandler) ErrHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			if err := f(w, r); err != nil {
+				return err
+			}
+			if next == nil {
+				return nil
+			}
+			return next(w, r)
+		}
+	}
+}
+
+// errPostMiddleware returns the middleware which executes f after next.
+func errPostMiddleware(f ErrHandler) ErrMiddleware {
+	return func(next ErrHandler) ErrHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			if next != nil {
+				if err := next(w, r); err != nil {
+					return err
+				}
+			}
+			return f(w, r)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package chainist
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeErrMiddleware(s string) ErrMiddleware {
+	return func(next ErrHandler) ErrHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			if _, err := w.Write([]byte(s)); err != nil {
+				return err
+			}
+			return next(w, r)
+		}
+	}
+}
+
+func writeErrHandler(s string, err error) ErrHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if _, werr := w.Write([]byte(s)); werr != nil {
+			return werr
+		}
+		return err
+	}
+}
+
+func TestNewErrChain(t *testing.T) {
+	{
+		c := NewErrChain()
+		assert.Equal(t, 0, c.Len())
+	}
+	{
+		c := NewErrChain(writeErrMiddleware("a")).SetHandlerFunc(writeErrHandler("h", nil))
+		assert.Equal(t, 2, c.Len())
+	}
+	{
+		c := NewErrChain(writeErrMiddleware("a"), nil)
+		assert.Nil(t, c)
+	}
+}
+
+func TestErrChainAppend(t *testing.T) {
+	c := NewErrChain()
+	c.Append(nil).AppendPreFunc(nil).AppendPostFunc(nil)
+	assert.Equal(t, 0, c.Len())
+
+	c.Append(writeErrMiddleware("a"))
+	c.AppendPreFunc(writeErrHandler("pre", nil))
+	c.AppendPostFunc(writeErrHandler("post", nil))
+	w := serveRecorder(c.ChainFunc(writeErrHandler("h", nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "aprehpost", w.Body.String())
+}
+
+func TestErrChainInsert(t *testing.T) {
+	c := NewErrChain()
+	c.Insert(nil, 0)
+	assert.Equal(t, 0, c.Len())
+
+	c.Insert(writeErrMiddleware("a"), 0)
+	c.Insert(writeErrMiddleware("b"), -1)
+	c.Insert(writeErrMiddleware("c"), 1)
+	w := serveRecorder(c.ChainFunc(nil))
+	assert.Equal(t, "bca", w.Body.String())
+}
+
+func TestErrChainExtend(t *testing.T) {
+	c := NewErrChain()
+	c.Extend(writeErrMiddleware("a"), nil, writeErrMiddleware("b"))
+	c.Join(nil)
+	c.Join(NewErrChain(writeErrMiddleware("c")).SetHandlerFunc(writeErrHandler("x", nil)))
+	c.SetHandlerFunc(writeErrHandler("h", nil))
+	assert.Equal(t, 4, c.Len())
+	w := serveRecorder(c.Chain())
+	assert.Equal(t, "abch", w.Body.String())
+}
+
+func TestErrChainClone(t *testing.T) {
+	base := NewErrChain(writeErrMiddleware("a")).SetHandlerFunc(writeErrHandler("h", nil))
+	c := base.Clone().Insert(writeErrMiddleware("b"), 0).SetHandler(writeErrHandler("x", nil))
+	assert.Equal(t, 2, base.Len())
+	assert.Equal(t, "ah", serveRecorder(base.Chain()).Body.String())
+	assert.Equal(t, "bax", serveRecorder(c.Chain()).Body.String())
+}
+
+func TestErrChainError(t *testing.T) {
+	errTest := errors.New("test")
+	{
+		// errors of pre-funcs abort the chain
+		c := NewErrChain()
+		c.AppendPreFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return NewHTTPError(http.StatusUnauthorized, nil)
+		})
+		c.AppendPostFunc(writeErrHandler("post", nil))
+		w := serveRecorder(c.ChainFunc(writeErrHandler("h", nil)))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	}
+	{
+		// post-funcs are skipped on errors
+		var got error
+		c := NewErrChain()
+		c.AppendPostFunc(writeErrHandler("post", nil))
+		c.SetErrorHandler(ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+		}))
+		w := serveRecorder(c.ChainFunc(writeErrHandler("h", errTest)))
+		assert.Equal(t, "h", w.Body.String())
+		assert.Equal(t, errTest, got)
+	}
+	{
+		// errors of post-funcs
+		var got error
+		c := NewErrChain()
+		c.AppendPostFunc(writeErrHandler("post", errTest))
+		c.SetErrorHandler(ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+			info, ok := ResponseInfo(w)
+			assert.True(t, ok)
+			assert.True(t, info.Committed())
+		}))
+		w := serveRecorder(c.ChainFunc(writeErrHandler("h", nil)))
+		assert.Equal(t, "hpost", w.Body.String())
+		assert.Equal(t, errTest, got)
+	}
+}
+
+func TestAdaptMiddleware(t *testing.T) {
+	errTest := errors.New("test")
+	{
+		assert.Nil(t, AdaptMiddleware(nil))
+	}
+	{
+		var got error
+		c := NewErrChain(AdaptMiddleware(writeMiddleware("m")))
+		c.SetErrorHandler(ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+		}))
+		w := serveRecorder(c.ChainFunc(writeErrHandler("h", errTest)))
+		assert.Equal(t, "mh", w.Body.String())
+		assert.Equal(t, errTest, got)
+	}
+	{
+		// middleware which does not invoke next
+		c := NewErrChain(AdaptMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(handlerFunc1)
+		}))
+		w := serveRecorder(c.ChainFunc(writeErrHandler("h", errTest)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "f1", w.Body.String())
+	}
+	{
+		// middleware which returns nil handler
+		h := AdaptMiddleware(func(next http.Handler) http.Handler { return nil })(writeErrHandler("h", errTest))
+		assert.Nil(t, h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)))
+	}
+}
@@ -0,0 +1,147 @@
+package chainist
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorHandler converts errors returned by the handlers of ErrChain into responses.
+type ErrorHandler interface {
+	// HandleError writes the response for the error.
+	// err is never nil.
+	HandleError(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// ErrorHandlerFunc is the function type which implements ErrorHandler.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// HandleError calls f(w, r, err).
+func (f ErrorHandlerFunc) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	f(w, r, err)
+}
+
+/*
+HTTPError is an error with a HTTP status code.
+
+    func getUser(w http.ResponseWriter, r *http.Request) error {
+        user, err := db.FindUser(chainist.PathParam(r, "id"))
+        if errors.Is(err, sql.ErrNoRows) {
+            return chainist.NewHTTPError(http.StatusNotFound, err)
+        }
+        ...
+    }
+*/
+type HTTPError struct {
+	// Status is the HTTP status code.
+	Status int
+
+	// Err is the underlying error.
+	Err error
+}
+
+// NewHTTPError returns a new HTTPError with the status code and the underlying error.
+func NewHTTPError(status int, err error) *HTTPError {
+	return &HTTPError{Status: status, Err: err}
+}
+
+// Error returns the message of the underlying error,
+// or the status text if the underlying error is nil.
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.Status)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the HTTP status code.
+func (e *HTTPError) StatusCode() int {
+	return e.Status
+}
+
+/*
+StatusOf returns the HTTP status code of the error.
+If any error in the chain of the error has the method `StatusCode() int`, the result is returned.
+200 OK is returned for nil and 500 Internal Server Error is returned for other errors.
+
+    chainist.StatusOf(chainist.NewHTTPError(http.StatusNotFound, nil)) // 404
+    chainist.StatusOf(errors.New("unknown"))                          // 500
+*/
+func StatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var s interface{ StatusCode() int }
+	if errors.As(err, &s) {
+		if code := s.StatusCode(); code >= 100 && code <= 999 {
+			return code
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+/*
+JSONErrorHandler is the ErrorHandler which responds errors as problem details of RFC 7807.
+Problems returned as errors are responded as they are.
+For other errors, the status code is determined with StatusOf(),
+or with Statuses if the status code is not given by the error.
+The message of the error is responded as "detail" only for 4xx status codes
+so that internal errors are not exposed to clients.
+The content type is negotiated in the same way as WriteProblem(),
+and nothing is written if the response has already been committed.
+
+    {"type":"about:blank","title":"Not Found","status":404,"detail":"user not found"}
+
+Usage:
+
+    chain := chainist.NewErrChain()
+    chain.SetErrorHandler(&chainist.JSONErrorHandler{
+        Statuses: []chainist.ErrorStatus{
+            {Err: sql.ErrNoRows, Status: http.StatusNotFound},
+        },
+    })
+*/
+type JSONErrorHandler struct {
+	// Statuses maps errors to HTTP status codes.
+	// Errors are matched with errors.Is() in order, and the first matched one is used.
+	Statuses []ErrorStatus
+}
+
+// ErrorStatus is the pair of an error and the HTTP status code for it.
+// See JSONErrorHandler.
+type ErrorStatus struct {
+	Err    error
+	Status int
+}
+
+// HandleError writes the error as a problem.
+func (h *JSONErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	if info, _ := ResponseInfo(w); info.Committed() {
+		return
+	}
+	var p *Problem
+	if !errors.As(err, &p) {
+		p = NewProblem(h.status(err), "")
+		if p.Status >= 400 && p.Status < 500 {
+			p.Detail = err.Error()
+		}
+	}
+	WriteProblem(w, r, p)
+}
+
+// status returns the status code for the error.
+func (h *JSONErrorHandler) status(err error) int {
+	var s interface{ StatusCode() int }
+	if !errors.As(err, &s) {
+		for _, s := range h.Statuses {
+			if errors.Is(err, s.Err) {
+				return s.Status
+			}
+		}
+	}
+	return StatusOf(err)
+}
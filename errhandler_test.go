@@ -0,0 +1,106 @@
+package chainist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("status %d", int(e))
+}
+
+func (e statusError) StatusCode() int {
+	return int(e)
+}
+
+func TestErrorHandlerFunc(t *testing.T) {
+	var got error
+	f := ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+	})
+	f.HandleError(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), context.Canceled)
+	assert.Equal(t, context.Canceled, got)
+}
+
+func TestHTTPError(t *testing.T) {
+	{
+		e := NewHTTPError(http.StatusNotFound, nil)
+		assert.Equal(t, "Not Found", e.Error())
+		assert.Nil(t, e.Unwrap())
+		assert.Equal(t, http.StatusNotFound, e.StatusCode())
+	}
+	{
+		e := NewHTTPError(http.StatusBadRequest, context.Canceled)
+		assert.Equal(t, "context canceled", e.Error())
+		assert.True(t, errors.Is(e, context.Canceled))
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	assert.Equal(t, http.StatusOK, StatusOf(nil))
+	assert.Equal(t, http.StatusInternalServerError, StatusOf(errors.New("test")))
+	assert.Equal(t, http.StatusNotFound, StatusOf(NewHTTPError(http.StatusNotFound, nil)))
+	assert.Equal(t, http.StatusConflict, StatusOf(fmt.Errorf("wrap: %w", statusError(http.StatusConflict))))
+	assert.Equal(t, http.StatusInternalServerError, StatusOf(statusError(0)))
+}
+
+func TestJSONErrorHandler(t *testing.T) {
+	errNotFound := errors.New("not found")
+	errGone := errors.New("gone")
+	h := &JSONErrorHandler{
+		Statuses: []ErrorStatus{
+			{Err: errNotFound, Status: http.StatusNotFound},
+			{Err: errGone, Status: http.StatusGone},
+			{Err: errNotFound, Status: http.StatusConflict},
+		},
+	}
+	serve := func(err error) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.HandleError(w, httptest.NewRequest(http.MethodGet, "/", nil), err)
+		return w
+	}
+	{
+		w := serve(NewHTTPError(http.StatusBadRequest, errors.New("invalid id")))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid id"}`, w.Body.String())
+	}
+	{
+		w := serve(fmt.Errorf("user 1: %w", errNotFound))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"user 1: not found"}`, w.Body.String())
+	}
+	{
+		// the first matched status is used
+		w := serve(errors.Join(errGone, errNotFound))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+	{
+		// the status code of the error precedes Statuses
+		w := serve(NewHTTPError(http.StatusGone, errNotFound))
+		assert.Equal(t, http.StatusGone, w.Code)
+	}
+	{
+		// internal errors are not exposed
+		w := serve(errors.New("db password is wrong"))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, w.Body.String())
+	}
+	{
+		// committed responses are not modified
+		w := httptest.NewRecorder()
+		rw := recordWriter(w)
+		rw.WriteHeader(http.StatusAccepted)
+		h.HandleError(rw, httptest.NewRequest(http.MethodGet, "/", nil), errNotFound)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "", w.Body.String())
+	}
+}
@@ -0,0 +1,35 @@
+package chainist
+
+import "errors"
+
+var (
+	// ErrNilMiddleware is the error returned when nil is given as middleware.
+	ErrNilMiddleware = errors.New("chainist: nil middleware")
+
+	// ErrEmptyChain is the error returned when building a chain
+	// which has neither middleware nor a handler function.
+	ErrEmptyChain = errors.New("chainist: empty chain")
+
+	// ErrNoHandler is the error returned when building a chain
+	// which does not have a handler function at the edge of the chain.
+	ErrNoHandler = errors.New("chainist: no handler function")
+
+	// ErrMissingDependency is the error returned when the ordering constraints
+	// of a chain refer to middleware which is not found in the chain.
+	ErrMissingDependency = errors.New("chainist: missing dependency")
+
+	// ErrDependencyCycle is the error returned when the ordering constraints
+	// of a chain can not be satisfied because of a cycle.
+	ErrDependencyCycle = errors.New("chainist: dependency cycle")
+
+	// ErrInvalidRoute is the error panicked when registering a route
+	// which has an invalid pattern or conflicts with registered routes.
+	ErrInvalidRoute = errors.New("chainist: invalid route")
+
+	// ErrUnknownMiddleware is the error returned when a chain configuration
+	// refers to middleware which is not registered in the registry.
+	ErrUnknownMiddleware = errors.New("chainist: unknown middleware")
+
+	// ErrInvalidConfig is the error returned when a chain configuration is malformed.
+	ErrInvalidConfig = errors.New("chainist: invalid config")
+)
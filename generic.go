@@ -0,0 +1,197 @@
+package chainist
+
+/*
+GenericMiddleware is the middleware for handlers of any types.
+Middleware is the instantiation of this type for http.Handler.
+
+    type RPCHandler func(ctx context.Context, req *Request) (*Response, error)
+
+    func LogRPC(next RPCHandler) RPCHandler {
+        return func(ctx context.Context, req *Request) (*Response, error) {
+            log.Println(req.Method)
+            return next(ctx, req)
+        }
+    }
+*/
+type GenericMiddleware[H any] func(h H) H
+
+/*
+GenericChain is the middleware chain for handlers of any types, e.g. RPC handlers,
+message consumers or command handlers.
+This provides the same Append/Insert/Extend/Join operations as Chain.
+Chain composes its middleware with GenericChain[http.Handler] after resolving the ordering constraints,
+and holds names, ordering constraints and the features specific to net/http on top of it.
+Middleware is the instantiation of GenericMiddleware for http.Handler,
+so the same middleware can be used with both Chain and GenericChain[http.Handler].
+Operations never modify the backing array of Middleware, so chains can share middleware safely.
+
+    chain := chainist.NewGenericChain[RPCHandler](LogRPC, AuthRPC)
+    handler := chain.Then(serveRPC)
+*/
+type GenericChain[H any] struct {
+	// Middleware is the list of middleware.
+	// This can contain nil values but they are ignored when calling Then().
+	Middleware []GenericMiddleware[H]
+
+	// Handler is the handler at the edge of the chain.
+	// This is used when calling Chain().
+	Handler H
+}
+
+/*
+NewGenericChain creates a new middleware chain for the handler type H.
+If nil is contained in the given arguments, nil is returned.
+
+    chain := chainist.NewGenericChain[RPCHandler](LogRPC, AuthRPC)
+*/
+func NewGenericChain[H any](ms ...GenericMiddleware[H]) *GenericChain[H] {
+	for _, m := range ms {
+		if m == nil {
+			return nil
+		}
+	}
+	return &GenericChain[H]{Middleware: append([]GenericMiddleware[H](nil), ms...)}
+}
+
+/*
+Append middleware at the last of the chain.
+If nil is given, then the chain will be returned without adding it.
+
+    chain.Append(LogRPC).Append(AuthRPC)
+*/
+func (c *GenericChain[H]) Append(m GenericMiddleware[H]) *GenericChain[H] {
+	if m == nil {
+		return c
+	}
+	c.Middleware = insertAt(c.Middleware, len(c.Middleware), m)
+	return c
+}
+
+/*
+Insert middleware at the designated position of the chain.
+If nil is given, then the chain will be returned without inserting it.
+Positions are handled in the same way as Chain.Insert().
+
+    // insert AuthRPC at the first of the chain
+    chain.Insert(AuthRPC, 0)
+*/
+func (c *GenericChain[H]) Insert(m GenericMiddleware[H], i int) *GenericChain[H] {
+	if m == nil {
+		return c
+	}
+	c.Middleware = insertAt(c.Middleware, i, m)
+	return c
+}
+
+/*
+Extend the chain with the given middleware.
+nil is ignored.
+
+    chain.Extend(LogRPC, AuthRPC)
+*/
+func (c *GenericChain[H]) Extend(ms ...GenericMiddleware[H]) *GenericChain[H] {
+	n := make([]GenericMiddleware[H], 0, len(c.Middleware)+len(ms))
+	n = append(n, c.Middleware...)
+	for _, m := range ms {
+		if m != nil {
+			n = append(n, m)
+		}
+	}
+	c.Middleware = n
+	return c
+}
+
+/*
+SetHandler sets the handler at the edge of the chain.
+
+    chain.SetHandler(serveRPC)
+    handler := chain.Chain()
+*/
+func (c *GenericChain[H]) SetHandler(h H) *GenericChain[H] {
+	c.Handler = h
+	return c
+}
+
+/*
+Join the middleware of the other chain at the last of the chain.
+The handler of the other chain is not used.
+If nil is given, then the chain will be returned as it is.
+
+    chain.Join(anotherChain)
+*/
+func (c *GenericChain[H]) Join(o *GenericChain[H]) *GenericChain[H] {
+	if o == nil {
+		return c
+	}
+	return c.Extend(o.Middleware...)
+}
+
+/*
+Clone returns a copy of the chain.
+The copy does not share the slice of middleware with the chain, so modifying one does not affect the other.
+
+    base := chainist.NewGenericChain[RPCHandler](LogRPC)
+
+    // base still has LogRPC only
+    admin := base.Clone().Append(AuthRPC)
+*/
+func (c *GenericChain[H]) Clone() *GenericChain[H] {
+	n := *c
+	n.Middleware = append([]GenericMiddleware[H](nil), c.Middleware...)
+	return &n
+}
+
+// Len returns the number of middleware in the chain.
+func (c *GenericChain[H]) Len() int {
+	return len(c.Middleware)
+}
+
+/*
+Then returns the handler wrapped by the middleware of the chain.
+The first middleware is the outermost.
+
+    handler := chain.Then(serveRPC)
+*/
+func (c *GenericChain[H]) Then(h H) H {
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		if m := c.Middleware[i]; m != nil {
+			h = m(h)
+		}
+	}
+	return h
+}
+
+/*
+Chain returns the handler set with SetHandler() wrapped by the middleware of the chain.
+This is the same as Then(c.Handler).
+
+    chain.SetHandler(serveRPC)
+    handler := chain.Chain()
+*/
+func (c *GenericChain[H]) Chain() H {
+	return c.Then(c.Handler)
+}
+
+// insertAt returns a new slice which has v inserted into s at the position i.
+// v is added at the first if i is negative, and at the last if i exceeds the length.
+// The backing array of s is never modified, so it can be shared safely.
+func insertAt[T any](s []T, i int, v T) []T {
+	if i > len(s) {
+		i = len(s)
+	}
+	if i < 0 {
+		i = 0
+	}
+	n := make([]T, 0, len(s)+1)
+	n = append(n, s[:i]...)
+	n = append(n, v)
+	return append(n, s[i:]...)
+}
+
+// removeAt returns a new slice which has the i-th element of s removed.
+// The backing array of s is never modified, so it can be shared safely.
+func removeAt[T any](s []T, i int) []T {
+	n := make([]T, 0, len(s)-1)
+	n = append(n, s[:i]...)
+	return append(n, s[i+1:]...)
+}
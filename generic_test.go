@@ -0,0 +1,138 @@
+package chainist
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stringHandler func(s string) string
+
+func stringMiddleware(name string) GenericMiddleware[stringHandler] {
+	return func(next stringHandler) stringHandler {
+		return func(s string) string {
+			return next(s + name)
+		}
+	}
+}
+
+func echo(s string) string {
+	return s
+}
+
+func TestNewGenericChain(t *testing.T) {
+	{
+		c := NewGenericChain[stringHandler]()
+		assert.Equal(t, 0, c.Len())
+	}
+	{
+		c := NewGenericChain(stringMiddleware("a"), stringMiddleware("b"))
+		assert.Equal(t, 2, c.Len())
+	}
+	{
+		c := NewGenericChain(stringMiddleware("a"), nil)
+		assert.Nil(t, c)
+	}
+}
+
+func TestGenericChainAppend(t *testing.T) {
+	c := NewGenericChain[stringHandler]()
+	c.Append(nil)
+	assert.Equal(t, 0, c.Len())
+	c.Append(stringMiddleware("a")).Append(stringMiddleware("b"))
+	assert.Equal(t, "ab", c.Then(echo)(""))
+}
+
+func TestGenericChainInsert(t *testing.T) {
+	{
+		c := NewGenericChain[stringHandler]()
+		c.Insert(nil, 0)
+		assert.Equal(t, 0, c.Len())
+	}
+	{
+		c := NewGenericChain[stringHandler]()
+		c.Insert(stringMiddleware("a"), -1)
+		c.Insert(stringMiddleware("b"), 99)
+		c.Insert(stringMiddleware("c"), 1)
+		c.Insert(stringMiddleware("d"), 0)
+		assert.Equal(t, "dacb", c.Then(echo)(""))
+	}
+}
+
+func TestGenericChainExtend(t *testing.T) {
+	c := NewGenericChain[stringHandler]()
+	c.Extend(stringMiddleware("a"), nil, stringMiddleware("b"))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, "ab", c.Then(echo)(""))
+}
+
+func TestGenericChainJoin(t *testing.T) {
+	c := NewGenericChain(stringMiddleware("a"))
+	c.Join(nil)
+	assert.Equal(t, 1, c.Len())
+	c.Join(NewGenericChain(stringMiddleware("b"), stringMiddleware("c")).SetHandler(echo))
+	assert.Equal(t, 3, c.Len())
+	assert.Nil(t, c.Handler)
+	assert.Equal(t, "abc", c.Then(echo)(""))
+}
+
+func TestGenericChainClone(t *testing.T) {
+	{
+		base := NewGenericChain(stringMiddleware("a")).SetHandler(echo)
+		c := base.Clone().Append(stringMiddleware("b"))
+		assert.Equal(t, 1, base.Len())
+		assert.Equal(t, "xab", c.Chain()("x"))
+	}
+	{
+		// chains sharing the backing array do not affect each other
+		base := &GenericChain[stringHandler]{Middleware: make([]GenericMiddleware[stringHandler], 1, 10)}
+		base.Middleware[0] = stringMiddleware("a")
+		c1 := &GenericChain[stringHandler]{Middleware: base.Middleware}
+		c2 := &GenericChain[stringHandler]{Middleware: base.Middleware}
+		c1.Append(stringMiddleware("b"))
+		c2.Extend(stringMiddleware("c"))
+		base.Join(NewGenericChain(stringMiddleware("d")))
+		assert.Equal(t, "ab", c1.Then(echo)(""))
+		assert.Equal(t, "ac", c2.Then(echo)(""))
+		assert.Equal(t, "ad", base.Then(echo)(""))
+	}
+}
+
+func TestGenericChainThen(t *testing.T) {
+	{
+		c := NewGenericChain[stringHandler]()
+		c.Middleware = append(c.Middleware, nil, stringMiddleware("a"))
+		assert.Equal(t, "xa", c.Then(echo)("x"))
+	}
+	{
+		c := NewGenericChain(stringMiddleware("a")).SetHandler(echo)
+		assert.Equal(t, "xa", c.Chain()("x"))
+	}
+	{
+		// Middleware is an instantiation of GenericMiddleware
+		c := NewGenericChain[http.Handler](writeMiddleware("m"))
+		w := serveRecorder(c.Then(http.HandlerFunc(handlerFunc1)))
+		assert.Equal(t, "mf1", w.Body.String())
+	}
+}
+
+func TestInsertAt(t *testing.T) {
+	s := make([]int, 3, 10)
+	s[0], s[1], s[2] = 1, 2, 3
+	assert.Equal(t, []int{9, 1, 2, 3}, insertAt(s, -1, 9))
+	assert.Equal(t, []int{1, 9, 2, 3}, insertAt(s, 1, 9))
+	assert.Equal(t, []int{1, 2, 3, 9}, insertAt(s, 99, 9))
+	assert.Equal(t, []int{9}, insertAt([]int(nil), 0, 9))
+	// the backing array is not modified
+	assert.Equal(t, []int{1, 2, 3}, s)
+	assert.Equal(t, []int{1, 2, 3, 0}, s[:4])
+}
+
+func TestRemoveAt(t *testing.T) {
+	s := []int{1, 2, 3}
+	assert.Equal(t, []int{2, 3}, removeAt(s, 0))
+	assert.Equal(t, []int{1, 3}, removeAt(s, 1))
+	assert.Equal(t, []int{1, 2}, removeAt(s, 2))
+	assert.Equal(t, []int{1, 2, 3}, s)
+}
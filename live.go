@@ -0,0 +1,112 @@
+package chainist
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+LiveChain is a http.Handler whose chain can be updated at runtime.
+Updates are applied to a copy of the chain and the composed handler is swapped atomically,
+so requests in flight finish with the previous version of the chain.
+Methods of LiveChain are safe for concurrent use.
+
+    chain := chainist.NewChain(handler1, handler2)
+    chain.SetHandlerFunc(handlerFuncAtEdge)
+
+    live, err := chainist.NewLiveChain(chain)
+    if err != nil {
+        panic(err)
+    }
+    go http.ListenAndServe(":8080", live)
+
+    // toggle debug middleware without restarts
+    err = live.Update(func(c *chainist.Chain) {
+        c.AppendNamed("debug", debugHandler)
+    })
+*/
+type LiveChain struct {
+	// mu serializes updates.
+	mu      sync.Mutex
+	current atomic.Pointer[liveVersion]
+}
+
+// liveVersion is a version of the chain and its composed handler.
+type liveVersion struct {
+	chain   *Chain
+	handler http.Handler
+	version uint64
+}
+
+/*
+NewLiveChain returns a new live chain which serves the given chain.
+The chain is copied, so modifying the given chain afterwards does not affect the live chain.
+Errors are returned in the same way as Chain.Build().
+
+    live, err := chainist.NewLiveChain(chain)
+*/
+func NewLiveChain(c *Chain) (*LiveChain, error) {
+	if c == nil {
+		return nil, ErrEmptyChain
+	}
+	c = c.Clone()
+	h, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	lc := &LiveChain{}
+	lc.current.Store(&liveVersion{chain: c, handler: h, version: 1})
+	return lc, nil
+}
+
+/*
+Update updates the chain with the given function.
+The function is called with a mutable copy of the current chain, and the copy is served after the function returned.
+The copy is made immutable again before being served if the current chain is immutable.
+If the updated chain can not be built, the error is returned and the current chain is kept.
+Updates are serialized, so the function must not call Update() itself.
+
+    err := live.Update(func(c *chainist.Chain) {
+        c.Remove("debug")
+    })
+*/
+func (lc *LiveChain) Update(f func(c *Chain)) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	cur := lc.current.Load()
+	c := cur.chain.Mutable()
+	if f != nil {
+		f(c)
+	}
+	if cur.chain.IsImmutable() {
+		c = c.Immutable()
+	}
+	h, err := c.Build()
+	if err != nil {
+		return err
+	}
+	lc.current.Store(&liveVersion{chain: c, handler: h, version: cur.version + 1})
+	return nil
+}
+
+/*
+Chain returns a copy of the chain currently served.
+Modifying the returned chain does not affect the live chain. Use Update() instead.
+
+    fmt.Println(live.Chain())
+*/
+func (lc *LiveChain) Chain() *Chain {
+	return lc.current.Load().chain.Clone()
+}
+
+// Version returns the version of the chain currently served.
+// The version starts from 1 and is incremented every time the chain is updated successfully.
+func (lc *LiveChain) Version() uint64 {
+	return lc.current.Load().version
+}
+
+// ServeHTTP serves the request with the chain currently served.
+func (lc *LiveChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lc.current.Load().handler.ServeHTTP(w, r)
+}
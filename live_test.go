@@ -0,0 +1,139 @@
+package chainist
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLiveChain(t *testing.T) {
+	{
+		lc, err := NewLiveChain(nil)
+		assert.Nil(t, lc)
+		assert.True(t, errors.Is(err, ErrEmptyChain))
+	}
+	{
+		lc, err := NewLiveChain(NewChain(writeMiddleware("a")))
+		assert.Nil(t, lc)
+		assert.True(t, errors.Is(err, ErrNoHandler))
+	}
+	{
+		c := NewChain(writeMiddleware("a")).SetHandlerFunc(handlerFunc1)
+		lc, err := NewLiveChain(c)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), lc.Version())
+
+		// the given chain is copied
+		c.Append(writeMiddleware("b"))
+		assert.Equal(t, 2, lc.Chain().Len())
+		assert.Equal(t, "af1", serveRecorder(lc).Body.String())
+	}
+}
+
+func TestLiveChainUpdate(t *testing.T) {
+	c := NewChain().AppendNamed("a", writeMiddleware("a")).SetHandlerFunc(handlerFunc1)
+	lc, err := NewLiveChain(c)
+	assert.NoError(t, err)
+	{
+		err := lc.Update(func(c *Chain) {
+			c.AppendNamed("debug", writeMiddleware("debug"))
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), lc.Version())
+		assert.Equal(t, "adebugf1", serveRecorder(lc).Body.String())
+	}
+	{
+		// the current chain is kept on errors
+		err := lc.Update(func(c *Chain) {
+			c.Remove("debug")
+			c.After("a", "missing")
+		})
+		assert.True(t, errors.Is(err, ErrMissingDependency))
+		assert.Equal(t, uint64(2), lc.Version())
+		assert.True(t, lc.Chain().Has("debug"))
+		assert.Equal(t, "adebugf1", serveRecorder(lc).Body.String())
+	}
+	{
+		assert.NoError(t, lc.Update(nil))
+		assert.Equal(t, uint64(3), lc.Version())
+	}
+	{
+		// modifying the returned chain does not affect the live chain
+		lc.Chain().Remove("debug")
+		assert.Equal(t, "adebugf1", serveRecorder(lc).Body.String())
+	}
+	{
+		// immutable chains are updated and kept immutable
+		lc, err := NewLiveChain(NewChain().SetHandlerFunc(handlerFunc1).Immutable())
+		assert.NoError(t, err)
+		err = lc.Update(func(c *Chain) {
+			c.AppendNamed("debug", writeMiddleware("debug"))
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), lc.Version())
+		assert.True(t, lc.Chain().Has("debug"))
+		assert.True(t, lc.Chain().IsImmutable())
+		assert.Equal(t, "debugf1", serveRecorder(lc).Body.String())
+	}
+}
+
+func TestLiveChainInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := NewChain().AppendNamed("block", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			next.ServeHTTP(w, r)
+		})
+	})
+	c.SetHandlerFunc(handlerFunc1)
+	lc, err := NewLiveChain(c)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var body string
+	go func() {
+		defer wg.Done()
+		body = serveRecorder(lc).Body.String()
+	}()
+	<-started
+	assert.NoError(t, lc.Update(func(c *Chain) {
+		c.Replace("block", writeMiddleware("new"))
+	}))
+	close(release)
+	wg.Wait()
+
+	// the request in flight finishes with the previous version
+	assert.Equal(t, "f1", body)
+	assert.Equal(t, "newf1", serveRecorder(lc).Body.String())
+}
+
+func TestLiveChainConcurrent(t *testing.T) {
+	lc, err := NewLiveChain(NewChain().SetHandlerFunc(handlerFunc1))
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = lc.Update(func(c *Chain) {
+				c.Append(writeMiddleware("m"))
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			lc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(11), lc.Version())
+	assert.Equal(t, 11, lc.Chain().Len())
+}
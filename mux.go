@@ -0,0 +1,223 @@
+package chainist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+/*
+Mux is a http.Handler which wraps http.ServeMux to register handlers with chains.
+Patterns follow the syntax of http.ServeMux introduced in Go 1.22, e.g. "GET /users/{id}".
+
+The mux has its own global chain which is wrapped around the whole mux.
+Middleware in the global chain can get the pattern and the path values of the request
+with RoutePattern() and PathParam() (or r.PathValue()) though it runs before the mux dispatches the request.
+They are given to a copy of the request, and the request given to the mux is not modified.
+If the global chain modifies the request, e.g. rewrites the URL, RoutePattern() reports
+the pattern which actually matched after the mux dispatched the request.
+
+    mux := chainist.NewMux(loggingHandler)
+    mux.Handle("GET /healthz", nil, healthzHandlerFunc)
+    mux.Handle("GET /users/{id}", chainist.NewChain(authHandler), userHandlerFunc)
+
+    http.ListenAndServe(":8080", mux)
+*/
+type Mux struct {
+	mux   *http.ServeMux
+	chain *Chain
+
+	once    sync.Once
+	handler http.Handler
+
+	// mu guards the fields below, which are modified by Handle().
+	// err is not modified after the global chain is composed, so it can be read without the lock after that.
+	mu       sync.RWMutex
+	composed bool
+	err      error
+	patterns map[string]bool
+}
+
+/*
+NewMux returns a new mux which wraps a new http.ServeMux.
+The given middleware is used as the global chain. Nil middleware is ignored.
+
+    mux := chainist.NewMux(handler1, handler2)
+*/
+func NewMux(ms ...Middleware) *Mux {
+	return &Mux{
+		mux:      http.NewServeMux(),
+		chain:    NewChain().Extend(ms...),
+		patterns: map[string]bool{},
+	}
+}
+
+/*
+Chain returns the global chain of the mux.
+The handler function of the chain is not used.
+The global chain is composed when the mux serves the first request,
+so middleware must be added before that.
+
+    mux.Chain().AppendNamed("recover", chainist.Recover(nil))
+*/
+func (m *Mux) Chain() *Chain {
+	return m.chain
+}
+
+// ServeMux returns the underlying http.ServeMux.
+// Handlers registered directly to it are served through the global chain,
+// but the global chain can get only the pattern of the request, not the path values, for them.
+func (m *Mux) ServeMux() *http.ServeMux {
+	return m.mux
+}
+
+/*
+Handle registers the handler function with the chain for the pattern.
+The handler is built with c.BuildFunc(f), and f is registered as it is if c is nil.
+If the chain is misconfigured, e.g. its ordering constraints can not be satisfied,
+the pattern is not registered and the error is reported by Build(), or the mux panics at the first request.
+Once the mux started serving, this panics with the error instead so that the mux keeps serving other patterns.
+This panics if the pattern is invalid or conflicts with registered patterns as http.ServeMux does,
+or the handler function is nil.
+
+    chain := chainist.NewChain(authHandler)
+    mux.Handle("GET /users/{id}", chain, userHandlerFunc)
+    mux.Handle("POST /users", chain, createUserHandlerFunc)
+*/
+func (m *Mux) Handle(pattern string, c *Chain, f http.HandlerFunc) {
+	if f == nil {
+		panic("chainist: nil handler function for " + pattern)
+	}
+	var h http.Handler = f
+	var err error
+	if c != nil {
+		h, err = c.BuildFunc(f)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		err = fmt.Errorf("%s: %w", pattern, err)
+		if m.composed {
+			panic(err)
+		}
+		if m.err == nil {
+			m.err = err
+		}
+		return
+	}
+	m.mux.Handle(pattern, muxRoute(pattern, h))
+	m.patterns[pattern] = true
+}
+
+/*
+Build composes the global chain and returns the mux as a http.Handler.
+Misconfiguration of the global chain and the chains given to Handle() is reported as an error.
+Without calling this, the global chain is composed at the first request and the mux panics on misconfiguration.
+*/
+func (m *Mux) Build() (http.Handler, error) {
+	m.once.Do(m.compose)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m, nil
+}
+
+// ServeHTTP serves the request with the global chain and the underlying http.ServeMux.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.once.Do(m.compose)
+	if m.err != nil {
+		panic(m.err)
+	}
+
+	r, rm := withRouterMatch(r)
+	rm.names, rm.values = nil, nil
+	// the path values are set by http.ServeMux only when it dispatches the request,
+	// so the copy of the request which has them is passed to the global chain
+	pr, pattern := m.match(r)
+	rm.pattern = pattern
+	if pr != nil {
+		r = pr
+	}
+	m.handler.ServeHTTP(w, r)
+}
+
+// compose composes the global chain with the underlying http.ServeMux.
+// The chain is built even if it has no middleware, because features such as recovery can be enabled.
+func (m *Mux) compose() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.composed = true
+	if m.err != nil {
+		return
+	}
+	m.handler, m.err = m.chain.BuildFunc(m.dispatch)
+}
+
+// dispatch dispatches the request to the handler registered to the underlying http.ServeMux.
+// The request may be modified by the global chain, so the pattern is looked up again.
+func (m *Mux) dispatch(w http.ResponseWriter, r *http.Request) {
+	if rm, ok := r.Context().Value(routeKey{}).(*routeMatch); ok {
+		_, rm.pattern = m.mux.Handler(r)
+	}
+	m.mux.ServeHTTP(w, r)
+}
+
+type muxMatchKey struct{}
+
+// muxMatch is the result of matching a request with the underlying http.ServeMux.
+type muxMatch struct {
+	r       *http.Request
+	pattern string
+}
+
+// match returns the pattern which matches the request in the underlying http.ServeMux.
+// If the pattern is registered with Handle(), the copy of the request is dispatched to the mux
+// without invoking the handler, and the copy which has the path values set by the mux is returned.
+// Otherwise nil is returned, because handlers registered directly to the mux can not be stopped.
+func (m *Mux) match(r *http.Request) (*http.Request, string) {
+	_, pattern := m.mux.Handler(r)
+	m.mu.RLock()
+	registered := m.patterns[pattern]
+	m.mu.RUnlock()
+	if !registered {
+		return nil, pattern
+	}
+
+	mm := &muxMatch{}
+	m.mux.ServeHTTP(discardWriter{}, r.WithContext(context.WithValue(r.Context(), muxMatchKey{}, mm)))
+	if mm.r == nil {
+		return nil, ""
+	}
+	return mm.r.WithContext(r.Context()), mm.pattern
+}
+
+// muxRoute returns the handler registered to the underlying http.ServeMux for the pattern.
+// It reports the request to match() instead of serving it if the request is being matched,
+// and sets the pattern of the request before serving it otherwise.
+func muxRoute(pattern string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mm, ok := r.Context().Value(muxMatchKey{}).(*muxMatch); ok {
+			mm.r, mm.pattern = r, pattern
+			return
+		}
+		if rm, ok := r.Context().Value(routeKey{}).(*routeMatch); ok {
+			rm.pattern = pattern
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// discardWriter is the response writer which discards responses.
+type discardWriter struct{}
+
+func (discardWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (discardWriter) WriteHeader(int) {}
@@ -0,0 +1,180 @@
+package chainist
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMux(t *testing.T) {
+	{
+		m := NewMux()
+		assert.NotNil(t, m.ServeMux())
+		assert.Equal(t, 0, m.Chain().Len())
+		assert.Equal(t, 0, NewMux(nil).Chain().Len())
+		assert.Panics(t, func() { m.Handle("GET /", nil, nil) })
+		assert.Panics(t, func() { m.Handle("GET /{", nil, handlerFunc1) })
+	}
+	{
+		m := NewMux()
+		m.Handle("GET /users/{id}", nil, paramHandlerFunc("id"))
+		m.Handle("GET /chain", NewChain(writeMiddleware("m")), handlerFunc1)
+
+		w := serveRouter(m, http.MethodGet, "/users/42")
+		assert.Equal(t, "GET /users/{id} id=42", w.Body.String())
+		w = serveRouter(m, http.MethodGet, "/chain")
+		assert.Equal(t, "mf1", w.Body.String())
+		w = serveRouter(m, http.MethodPost, "/users/42")
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		w = serveRouter(m, http.MethodGet, "/missing")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+	{
+		// misconfigured chains are reported without panicking at registration
+		m := NewMux()
+		c := NewChain().AppendNamed("a", plainMiddleware).After("a", "missing")
+		assert.NotPanics(t, func() { m.Handle("GET /a", c, handlerFunc1) })
+		h, err := m.Build()
+		assert.Nil(t, h)
+		assert.True(t, errors.Is(err, ErrMissingDependency))
+		assert.Contains(t, err.Error(), "GET /a: ")
+		assert.Panics(t, func() { serveRouter(m, http.MethodGet, "/a") })
+	}
+}
+
+func TestMuxServeMux(t *testing.T) {
+	var calls int
+	var pattern string
+	m := NewMux(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern = RoutePattern(r)
+			next.ServeHTTP(w, r)
+		})
+	})
+	m.ServeMux().HandleFunc("POST /pay/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+	})
+	m.Handle("GET /a", nil, handlerFunc1)
+
+	// handlers registered directly are invoked only once
+	w := serveRouter(m, http.MethodPost, "/pay/1")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "POST /pay/{id}", pattern)
+	w = serveRouter(m, http.MethodGet, "/a")
+	assert.Equal(t, "f1", w.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestMuxHandleWhileServing(t *testing.T) {
+	m := NewMux()
+	m.Handle("GET /a", nil, handlerFunc1)
+	h, err := m.Build()
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			serveRouter(h, http.MethodGet, "/a")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		m.Handle("GET /b/"+strconv.Itoa(i), nil, handlerFunc2)
+	}
+	<-done
+
+	// misconfigured chains are reported immediately without breaking other patterns
+	c := NewChain().AppendNamed("x", plainMiddleware).After("x", "missing")
+	assert.Panics(t, func() { m.Handle("GET /c", c, handlerFunc1) })
+	assert.Equal(t, "f1", serveRouter(h, http.MethodGet, "/a").Body.String())
+	assert.Equal(t, "f2", serveRouter(h, http.MethodGet, "/b/1").Body.String())
+}
+
+func TestMuxGlobalChain(t *testing.T) {
+	{
+		var pattern, id, rest string
+		m := NewMux(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pattern = RoutePattern(r)
+				id = PathParam(r, "id")
+				rest = r.PathValue("rest")
+				next.ServeHTTP(w, r)
+			})
+		})
+		m.Handle("GET /users/{id}/files/{rest...}", nil, paramHandlerFunc("id", "rest"))
+
+		w := serveRouter(m, http.MethodGet, "/users/a%2Fb/files/x/y%20z")
+		assert.Equal(t, "GET /users/{id}/files/{rest...} id=a/b rest=x/y z", w.Body.String())
+		assert.Equal(t, "GET /users/{id}/files/{rest...}", pattern)
+		assert.Equal(t, "a/b", id)
+		assert.Equal(t, "x/y z", rest)
+
+		serveRouter(m, http.MethodGet, "/missing")
+		assert.Equal(t, "", pattern)
+		assert.Equal(t, "", id)
+	}
+	{
+		// the given request is not modified
+		m := NewMux()
+		m.Handle("GET /users/{id}", nil, paramHandlerFunc("id"))
+		r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		assert.Equal(t, "GET /users/{id} id=42", w.Body.String())
+		assert.Equal(t, "", r.PathValue("id"))
+		assert.Equal(t, "", RoutePattern(r))
+	}
+	{
+		// the pattern is updated when the global chain rewrites the URL
+		var before, after string
+		m := NewMux(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				before = RoutePattern(r)
+				r = r.Clone(r.Context())
+				r.URL.Path = "/v2" + r.URL.Path
+				next.ServeHTTP(w, r)
+				after = RoutePattern(r)
+			})
+		})
+		m.Handle("GET /users/{id}", nil, paramHandlerFunc("id"))
+		m.Handle("GET /v2/users/{id}", nil, paramHandlerFunc("id"))
+
+		w := serveRouter(m, http.MethodGet, "/users/42")
+		assert.Equal(t, "GET /v2/users/{id} id=42", w.Body.String())
+		assert.Equal(t, "GET /users/{id}", before)
+		assert.Equal(t, "GET /v2/users/{id}", after)
+
+		serveRouter(m, http.MethodGet, "/v2/users/42")
+		assert.Equal(t, "", after)
+	}
+	{
+		m := NewMux()
+		m.Chain().AppendNamed("a", plainMiddleware).After("a", "missing")
+		h, err := m.Build()
+		assert.Nil(t, h)
+		assert.True(t, errors.Is(err, ErrMissingDependency))
+		assert.Panics(t, func() { serveRouter(m, http.MethodGet, "/") })
+	}
+	{
+		m := NewMux(writeMiddleware("g"))
+		m.Handle("/", nil, handlerFunc1)
+		h, err := m.Build()
+		assert.Nil(t, err)
+		assert.Equal(t, "gf1", serveRouter(h, http.MethodGet, "/").Body.String())
+	}
+	{
+		// features of the global chain are enabled without middleware
+		m := NewMux()
+		m.Chain().EnableRecovery(nil)
+		m.Handle("/", nil, panicHandlerFunc)
+		var w *httptest.ResponseRecorder
+		assert.NotPanics(t, func() { w = serveRouter(m, http.MethodGet, "/") })
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+}
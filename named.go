@@ -0,0 +1,252 @@
+package chainist
+
+/*
+AppendNamed appends middleware at the last of the chain with a name.
+The name can be used to look up, remove, replace the middleware
+or to insert other middleware around it afterwards.
+If nil is given as middleware, or the name is already used in the chain,
+the chain will be returned without adding it.
+Empty name is allowed and it is same as calling Append().
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+         .AppendNamed("logging", logHandler)
+
+    // replace the auth handler with another one
+    chain.Replace("auth", anotherAuthHandler)
+*/
+func (c *Chain) AppendNamed(name string, m Middleware) *Chain {
+	if c.Has(name) {
+		return c
+	}
+	return c.add(m, entry{name: name})
+}
+
+/*
+Has reports whether the chain has middleware with the given name.
+Empty name always results in false.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+
+    // this shows true
+    println(chain.Has("auth"))
+*/
+func (c *Chain) Has(name string) bool {
+	return c.Index(name) >= 0
+}
+
+/*
+Index returns the position of the middleware with the given name in the chain.
+-1 is returned if the chain does not have the middleware with the name.
+
+    chain := chainist.NewChain(handler1)
+    chain.AppendNamed("auth", authHandler)
+
+    // this shows 1
+    println(chain.Index("auth"))
+*/
+func (c *Chain) Index(name string) int {
+	if name == "" {
+		return -1
+	}
+	for i := range c.Middleware {
+		if c.entryAt(i).name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+Remove removes the middleware with the given name from the chain.
+Ordering constraints declared for the middleware with After() or Before() are also removed.
+If the chain does not have the middleware with the name, the chain will be returned as it is.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+
+    // chain.Middleware will be empty
+    chain.Remove("auth")
+*/
+func (c *Chain) Remove(name string) *Chain {
+	i := c.Index(name)
+	if i < 0 {
+		return c
+	}
+	c, done := c.mutate()
+	defer done()
+	c.syncEntries()
+	c.Middleware = removeAt(c.Middleware, i)
+	c.entries = removeAt(c.entries, i)
+	c.markSynced()
+	edges := make([]edge, 0, len(c.edges))
+	for _, e := range c.edges {
+		if e.owner != name {
+			edges = append(edges, e)
+		}
+	}
+	c.edges = edges
+	return c
+}
+
+/*
+Replace replaces the middleware with the given name.
+The name is kept for the new middleware.
+If nil is given as middleware, or the chain does not have the middleware with the name,
+the chain will be returned as it is.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+
+    // chain.Middleware[0] will be anotherAuthHandler
+    chain.Replace("auth", anotherAuthHandler)
+*/
+func (c *Chain) Replace(name string, m Middleware) *Chain {
+	i := c.Index(name)
+	if m == nil || i < 0 {
+		return c
+	}
+	c, done := c.mutate()
+	defer done()
+	c.Middleware[i] = m
+	return c
+}
+
+/*
+InsertBefore inserts middleware just before the middleware with the given name.
+If nil is given as middleware, or the chain does not have the middleware with the name,
+the chain will be returned without inserting it.
+
+    chain := chainist.NewChain(handler1)
+    chain.AppendNamed("auth", authHandler)
+
+    // chain will have handler1,handler2,authHandler with this order
+    chain.InsertBefore("auth", handler2)
+*/
+func (c *Chain) InsertBefore(name string, m Middleware) *Chain {
+	i := c.Index(name)
+	if i < 0 {
+		return c
+	}
+	return c.Insert(m, i)
+}
+
+/*
+InsertAfter inserts middleware just after the middleware with the given name.
+If nil is given as middleware, or the chain does not have the middleware with the name,
+the chain will be returned without inserting it.
+
+    chain := chainist.NewChain(handler1)
+    chain.AppendNamed("auth", authHandler)
+
+    // chain will have handler1,authHandler,handler2 with this order
+    chain.InsertAfter("auth", handler2)
+*/
+func (c *Chain) InsertAfter(name string, m Middleware) *Chain {
+	i := c.Index(name)
+	if i < 0 {
+		return c
+	}
+	return c.Insert(m, i+1)
+}
+
+// entry is the metadata of a middleware in a chain.
+type entry struct {
+	// name is the name of the middleware.
+	// Empty name means the middleware is not named.
+	name string
+
+	// kind is the kind of the middleware.
+	kind Kind
+
+	// fn is the function which the middleware is created from.
+	// This is nil for plain middleware.
+	fn any
+
+	// group is the label of the joined chain which the middleware came from.
+	// This is empty for the middleware added directly.
+	group string
+}
+
+// add appends middleware with its metadata.
+// nil middleware is ignored.
+func (c *Chain) add(m Middleware, e entry) *Chain {
+	if m == nil {
+		return c
+	}
+	c, done := c.mutate()
+	defer done()
+	c.syncEntries()
+	c.Middleware = append(c.Middleware, m)
+	c.entries = append(c.entries, e)
+	c.markSynced()
+	return c
+}
+
+// insert inserts middleware with its metadata at the position i.
+// See Insert() for the handling of positions.
+// nil middleware is ignored.
+func (c *Chain) insert(m Middleware, i int, e entry) *Chain {
+	if m == nil {
+		return c
+	}
+	c, done := c.mutate()
+	defer done()
+	c.syncEntries()
+	c.Middleware = insertAt(c.Middleware, i, m)
+	c.entries = insertAt(c.entries, i, e)
+	c.markSynced()
+	return c
+}
+
+// nameAt returns the name of the i-th middleware.
+// Empty string is returned if the middleware is not named.
+func (c *Chain) nameAt(i int) string {
+	return c.entryAt(i).name
+}
+
+// entryAt returns the metadata of the i-th middleware.
+// Zero value is returned for the middleware added without the methods of Chain.
+func (c *Chain) entryAt(i int) entry {
+	entries := c.validEntries()
+	if i < 0 || i >= len(entries) {
+		return entry{}
+	}
+	return entries[i]
+}
+
+// validEntries returns the metadata if it is still valid for Middleware.
+// Middleware can be modified directly by users, so the metadata is valid only while Middleware
+// shares the backing array with the slice which the metadata was made for.
+// Middleware replaced in place or appended without reallocation keeps the metadata,
+// and the appended middleware has no metadata. Otherwise, e.g. when middleware was prepended,
+// nil is returned instead of the metadata of wrong middleware.
+func (c *Chain) validEntries() []entry {
+	n := len(c.synced)
+	if len(c.Middleware) < n || (n > 0 && &c.Middleware[0] != &c.synced[0]) {
+		return nil
+	}
+	return c.entries
+}
+
+// forgotten reports whether names of the middleware were forgotten because of direct modifications of Middleware.
+func (c *Chain) forgotten() bool {
+	return len(c.synced) > 0 && c.validEntries() == nil
+}
+
+// syncEntries makes the length of entries equal to that of Middleware before modifying the chain.
+// The metadata is discarded if it is no longer valid. See validEntries().
+// Call markSynced() after modifying the chain.
+func (c *Chain) syncEntries() {
+	entries := c.validEntries()
+	for len(entries) < len(c.Middleware) {
+		entries = append(entries, entry{})
+	}
+	c.entries = entries
+}
+
+// markSynced records Middleware as the slice which entries are made for.
+func (c *Chain) markSynced() {
+	c.synced = c.Middleware
+}
@@ -0,0 +1,240 @@
+package chainist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendNamed(t *testing.T) {
+	{
+		c := NewChain()
+		c.AppendNamed("h1", nil)
+		assert.Equal(t, 0, len(c.Middleware))
+		assert.False(t, c.Has("h1"))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		assert.Equal(t, 1, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler1.Middleware), funcPointer(c.Middleware[0]))
+		assert.True(t, c.Has("h1"))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.AppendNamed("h1", handler2.Middleware)
+		assert.Equal(t, 1, len(c.Middleware))
+		assert.Equal(t, 0, c.Index("h1"))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("", handler1.Middleware)
+		c.AppendNamed("", handler2.Middleware)
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.False(t, c.Has(""))
+	}
+	{
+		c := &Chain{
+			Middleware: []Middleware{handler1.Middleware},
+		}
+		c.AppendNamed("h2", handler2.Middleware)
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, 1, c.Index("h2"))
+	}
+}
+
+func TestIndex(t *testing.T) {
+	{
+		c := NewChain()
+		assert.Equal(t, -1, c.Index("h1"))
+		assert.Equal(t, -1, c.Index(""))
+	}
+	{
+		c := NewChain(handler1.Middleware)
+		c.AppendNamed("h2", handler2.Middleware)
+		c.Insert(handler1.Middleware, 0)
+		assert.Equal(t, 2, c.Index("h2"))
+		assert.Equal(t, -1, c.Index("h1"))
+	}
+}
+
+func TestRemove(t *testing.T) {
+	{
+		c := NewChain(handler1.Middleware)
+		c.Remove("h1")
+		assert.Equal(t, 1, len(c.Middleware))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.AppendNamed("h2", handler2.Middleware)
+		c.Remove("h1")
+		assert.Equal(t, 1, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler2.Middleware), funcPointer(c.Middleware[0]))
+		assert.False(t, c.Has("h1"))
+		assert.Equal(t, 0, c.Index("h2"))
+	}
+}
+
+func TestReplace(t *testing.T) {
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.Replace("h1", nil)
+		assert.Equal(t, 1, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler1.Middleware), funcPointer(c.Middleware[0]))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.Replace("h2", handler2.Middleware)
+		assert.Equal(t, 1, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler1.Middleware), funcPointer(c.Middleware[0]))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.AppendPreFunc(handlerFunc1)
+		c.Replace("h1", handler2.PostMiddleware)
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler2.PostMiddleware), funcPointer(c.Middleware[0]))
+		assert.Equal(t, 0, c.Index("h1"))
+	}
+}
+
+func TestInsertBefore(t *testing.T) {
+	{
+		c := NewChain()
+		c.InsertBefore("h1", handler1.Middleware)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.InsertBefore("h1", nil)
+		assert.Equal(t, 1, len(c.Middleware))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.InsertBefore("h1", handler2.PostMiddleware)
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler2.PostMiddleware), funcPointer(c.Middleware[0]))
+		assert.Equal(t, 1, c.Index("h1"))
+	}
+}
+
+func TestInsertAfter(t *testing.T) {
+	{
+		c := NewChain()
+		c.InsertAfter("h1", handler1.Middleware)
+		assert.Equal(t, 0, len(c.Middleware))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.InsertAfter("h1", handler2.PostMiddleware)
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler2.PostMiddleware), funcPointer(c.Middleware[1]))
+		assert.Equal(t, 0, c.Index("h1"))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.AppendNamed("h2", handler2.Middleware)
+		c.InsertAfter("h1", handler2.PostMiddleware)
+		assert.Equal(t, 3, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler2.PostMiddleware), funcPointer(c.Middleware[1]))
+		assert.Equal(t, 2, c.Index("h2"))
+	}
+}
+
+func TestJoinNamed(t *testing.T) {
+	c1 := NewChain()
+	c1.AppendNamed("h1", handler1.Middleware)
+	c2 := NewChain()
+	c2.AppendNamed("h1", handler1.Middleware)
+	c2.AppendNamed("h2", handler2.Middleware)
+	c1.Join(c2)
+	assert.Equal(t, 3, len(c1.Middleware))
+	assert.Equal(t, 0, c1.Index("h1"))
+	assert.Equal(t, 2, c1.Index("h2"))
+
+	s := httptest.NewServer(c1.Remove("h1").Chain())
+	defer s.Close()
+
+	res, err := http.Get(s.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 200, res.StatusCode)
+}
+
+func TestNamedDirectModification(t *testing.T) {
+	{
+		// middleware replaced in place keeps the name
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.Middleware[0] = handler2.Middleware
+		assert.Equal(t, 0, c.Index("h1"))
+	}
+	{
+		// names are forgotten rather than pointing at wrong middleware
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.Middleware = append([]Middleware{handler2.Middleware}, c.Middleware...)
+		assert.False(t, c.Has("h1"))
+		c.Remove("h1")
+		assert.Equal(t, 2, len(c.Middleware))
+		assert.Equal(t, funcPointer(handler2.Middleware), funcPointer(c.Middleware[0]))
+
+		// names are available again for the middleware added after that
+		c.AppendNamed("h1", handler1.PreMiddleware)
+		assert.Equal(t, 2, c.Index("h1"))
+	}
+	{
+		// names are kept when middleware is appended without reallocation
+		c := &Chain{Middleware: make([]Middleware, 0, 10)}
+		c.AppendNamed("h1", handler1.Middleware)
+		c.AppendNamed("h2", handler2.Middleware)
+		c.Middleware = append(c.Middleware, handler1.PreMiddleware)
+		assert.Equal(t, 0, c.Index("h1"))
+		assert.Equal(t, 1, c.Index("h2"))
+	}
+	{
+		// names are forgotten but the constraints are reported
+		c := NewChain()
+		c.AppendNamed("a", writeMiddleware("a"))
+		c.AppendNamed("b", writeMiddleware("b"))
+		c.After("a", "b")
+		c.Middleware = append([]Middleware{writeMiddleware("c")}, c.Middleware...)
+		assert.False(t, c.Has("a"))
+		_, err := c.BuildFunc(handlerFunc1)
+		assert.ErrorIs(t, err, ErrMissingDependency)
+		assert.Panics(t, func() { c.ChainFunc(handlerFunc1) })
+		c.Append(writeMiddleware("d"))
+		_, err = c.BuildFunc(handlerFunc1)
+		assert.ErrorIs(t, err, ErrMissingDependency)
+	}
+	{
+		// chains without constraints are built without names
+		c := NewChain()
+		c.AppendNamed("a", writeMiddleware("a"))
+		c.Middleware = append([]Middleware{writeMiddleware("c")}, c.Middleware...)
+		h, err := c.BuildFunc(handlerFunc1)
+		assert.NoError(t, err)
+		assert.Equal(t, "caf1", serveRecorder(h).Body.String())
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("h1", handler1.Middleware)
+		c.AppendNamed("h2", handler2.Middleware)
+		c.Middleware = c.Middleware[1:]
+		assert.False(t, c.Has("h2"))
+		c.Middleware = nil
+		assert.False(t, c.Has("h1"))
+		assert.Equal(t, 0, len(c.Clone().Middleware))
+	}
+}
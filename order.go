@@ -0,0 +1,226 @@
+package chainist
+
+import (
+	"fmt"
+	"strings"
+)
+
+// edge is an ordering constraint between two named middleware.
+// Middleware named first must run before the one named then.
+// owner is the name of middleware which declared the constraint.
+type edge struct {
+	first string
+	then  string
+	owner string
+}
+
+/*
+After declares that the middleware with the given name must run after all of the named middleware given as deps.
+Constraints are resolved when getting middleware chains with Chain() or ChainFunc(),
+so the middleware does not need to exist in the chain at the time of declaration.
+Empty names are ignored.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+    chain.AppendNamed("request-id", requestIDHandler)
+
+    // requestIDHandler is invoked before authHandler
+    chain.After("auth", "request-id")
+*/
+func (c *Chain) After(name string, deps ...string) *Chain {
+	if name == "" {
+		return c
+	}
+	c, done := c.mutate()
+	defer done()
+	for _, dep := range deps {
+		if dep == "" {
+			continue
+		}
+		c.edges = append(c.edges, edge{first: dep, then: name, owner: name})
+	}
+	return c
+}
+
+/*
+Before declares that the middleware with the given name must run before all of the named middleware given as deps.
+Constraints are resolved when getting middleware chains with Chain() or ChainFunc(),
+so the middleware does not need to exist in the chain at the time of declaration.
+Empty names are ignored.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+    chain.AppendNamed("request-id", requestIDHandler)
+
+    // requestIDHandler is invoked before authHandler
+    chain.Before("request-id", "auth")
+*/
+func (c *Chain) Before(name string, deps ...string) *Chain {
+	if name == "" {
+		return c
+	}
+	c, done := c.mutate()
+	defer done()
+	for _, dep := range deps {
+		if dep == "" {
+			continue
+		}
+		c.edges = append(c.edges, edge{first: name, then: dep, owner: name})
+	}
+	return c
+}
+
+/*
+Resolve returns the middleware of the chain in the order they are invoked.
+The order is computed by topologically sorting the middleware with the constraints
+declared with After() and Before().
+Middleware without constraints keeps its position relative to others as much as possible.
+nil middleware is not contained in the result.
+
+An error wrapping ErrMissingDependency is returned if a constraint refers to unknown middleware,
+and an error wrapping ErrDependencyCycle is returned if the constraints have a cycle.
+
+    chain := chainist.NewChain()
+    chain.AppendNamed("auth", authHandler)
+    chain.AppendNamed("request-id", requestIDHandler)
+    chain.After("auth", "request-id")
+
+    // ms will be [requestIDHandler, authHandler]
+    ms, err := chain.Resolve()
+*/
+func (c *Chain) Resolve() ([]Middleware, error) {
+	order, err := c.resolve()
+	if err != nil {
+		return nil, err
+	}
+	ms := make([]Middleware, 0, len(order))
+	for _, i := range order {
+		ms = append(ms, c.Middleware[i])
+	}
+	return ms, nil
+}
+
+// resolve returns the positions of non-nil middleware in the order they are invoked.
+// Constraints are not checked if no constraints are declared, so this never fails for such chains.
+// Declared constraints can not be satisfied if names of the middleware are forgotten. See Chain.Middleware.
+func (c *Chain) resolve() ([]int, error) {
+	n := len(c.Middleware)
+
+	edges := c.edges
+	if len(edges) > 0 && c.forgotten() {
+		e := edges[0]
+		return nil, fmt.Errorf("%w: names of middleware required by %q are lost because Middleware was modified directly", ErrMissingDependency, e.owner)
+	}
+	if len(edges) == 0 {
+		positions := make([]int, 0, n)
+		for i, m := range c.Middleware {
+			if m != nil {
+				positions = append(positions, i)
+			}
+		}
+		return positions, nil
+	}
+
+	index := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		if name := c.nameAt(i); name != "" {
+			index[name] = i
+		}
+	}
+
+	// successors and the number of predecessors of each middleware
+	next := make([][]int, n)
+	degree := make([]int, n)
+	for _, e := range edges {
+		first, ok := index[e.first]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q required by %q is not found", ErrMissingDependency, e.first, e.owner)
+		}
+		then, ok := index[e.then]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q required by %q is not found", ErrMissingDependency, e.then, e.owner)
+		}
+		next[first] = append(next[first], then)
+		degree[then]++
+	}
+
+	done := make([]bool, n)
+	order := make([]int, 0, n)
+	for len(order) < n {
+		// pick the first middleware which has no remaining predecessors
+		// so that the original order is kept as much as possible
+		j := -1
+		for i := 0; i < n; i++ {
+			if !done[i] && degree[i] == 0 {
+				j = i
+				break
+			}
+		}
+		if j < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, c.cycle(next, done))
+		}
+		done[j] = true
+		order = append(order, j)
+		for _, k := range next[j] {
+			degree[k]--
+		}
+	}
+
+	positions := make([]int, 0, n)
+	for _, i := range order {
+		if c.Middleware[i] == nil {
+			continue
+		}
+		positions = append(positions, i)
+	}
+	return positions, nil
+}
+
+// cycle finds a cycle among the middleware which are not done yet
+// and returns it in the form of "a -> b -> a".
+// Every remaining middleware has at least one remaining predecessor,
+// so following predecessors always ends up with a cycle.
+func (c *Chain) cycle(next [][]int, done []bool) string {
+	prev := make([]int, len(next))
+	for i := range prev {
+		prev[i] = -1
+	}
+	start := -1
+	for i, ks := range next {
+		if done[i] {
+			continue
+		}
+		for _, k := range ks {
+			if !done[k] {
+				prev[k] = i
+				start = k
+			}
+		}
+	}
+
+	seen := map[int]int{}
+	path := []int{}
+	for i := start; ; i = prev[i] {
+		if p, ok := seen[i]; ok {
+			path = path[p:]
+			break
+		}
+		seen[i] = len(path)
+		path = append(path, i)
+	}
+
+	// path is in the reverse order of invocation.
+	// The cycle is shown from the middleware positioned first in the chain.
+	first := 0
+	for j := range path {
+		if path[j] < path[first] {
+			first = j
+		}
+	}
+	names := make([]string, 0, len(path)+1)
+	for j := 0; j <= len(path); j++ {
+		k := ((first-j)%len(path) + len(path)) % len(path)
+		names = append(names, fmt.Sprintf("%q", c.nameAt(path[k])))
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,174 @@
+package chainist
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeMiddleware(s string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := w.Write([]byte(s)); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			if next != nil {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func TestAfterBefore(t *testing.T) {
+	{
+		c := NewChain()
+		c.After("", "a").After("a", "").Before("", "a").Before("a", "")
+		assert.Equal(t, 0, len(c.edges))
+	}
+	{
+		c := NewChain()
+		c.After("a", "b", "c")
+		c.Before("d", "e")
+		assert.Equal(t, []edge{
+			{first: "b", then: "a", owner: "a"},
+			{first: "c", then: "a", owner: "a"},
+			{first: "d", then: "e", owner: "d"},
+		}, c.edges)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	{
+		c := NewChain()
+		ms, err := c.Resolve()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(ms))
+	}
+	{
+		c := &Chain{
+			Middleware: []Middleware{handler1.Middleware, nil, handler2.Middleware},
+		}
+		ms, err := c.Resolve()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(ms))
+		assert.Equal(t, funcPointer(handler1.Middleware), funcPointer(ms[0]))
+		assert.Equal(t, funcPointer(handler2.Middleware), funcPointer(ms[1]))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("a", handler1.PreMiddleware)
+		c.AppendNamed("b", handler2.PostMiddleware)
+		c.After("a", "b")
+		ms, err := c.Resolve()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(ms))
+		assert.Equal(t, funcPointer(handler2.PostMiddleware), funcPointer(ms[0]))
+		assert.Equal(t, funcPointer(handler1.PreMiddleware), funcPointer(ms[1]))
+	}
+	{
+		c := NewChain()
+		c.After("a", "b")
+		c.AppendNamed("a", handler1.Middleware)
+		_, err := c.Resolve()
+		assert.ErrorIs(t, err, ErrMissingDependency)
+		assert.Contains(t, err.Error(), `"b" required by "a"`)
+	}
+	{
+		c := NewChain()
+		c.Before("b", "a")
+		c.AppendNamed("a", handler1.Middleware)
+		_, err := c.Resolve()
+		assert.ErrorIs(t, err, ErrMissingDependency)
+		assert.Contains(t, err.Error(), `"b" required by "b"`)
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("a", handler1.Middleware)
+		c.AppendNamed("b", handler1.Middleware)
+		c.AppendNamed("c", handler1.Middleware)
+		c.After("a", "c").After("c", "b").After("b", "a")
+		_, err := c.Resolve()
+		assert.ErrorIs(t, err, ErrDependencyCycle)
+		assert.Contains(t, err.Error(), `"a" -> "b" -> "c" -> "a"`)
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("a", handler1.Middleware)
+		c.After("a", "a")
+		_, err := c.Resolve()
+		assert.ErrorIs(t, err, ErrDependencyCycle)
+		assert.Contains(t, err.Error(), `"a" -> "a"`)
+	}
+}
+
+func TestRemoveConstraints(t *testing.T) {
+	c := NewChain()
+	c.AppendNamed("a", handler1.Middleware)
+	c.AppendNamed("b", handler1.Middleware)
+	c.After("a", "b")
+	c.Before("b", "c")
+	c.Remove("a")
+	_, err := c.Resolve()
+	assert.ErrorIs(t, err, ErrMissingDependency)
+	c.AppendNamed("c", handler1.Middleware)
+	_, err = c.Resolve()
+	assert.NoError(t, err)
+}
+
+func TestChainFuncOrdered(t *testing.T) {
+	{
+		c := NewChain()
+		c.AppendNamed("auth", writeMiddleware("auth"))
+		c.AppendNamed("log", writeMiddleware("log"))
+		c.Append(writeMiddleware("x"))
+		c.AppendNamed("request-id", writeMiddleware("id"))
+		c.After("auth", "request-id").Before("log", "auth")
+
+		s := httptest.NewServer(c.ChainFunc(handlerFunc1))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := io.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "logxidauthf1", string(body))
+	}
+	{
+		c := NewChain()
+		c.AppendNamed("auth", writeMiddleware("auth"))
+		c.After("auth", "request-id")
+		assert.Panics(t, func() { c.Chain() })
+	}
+	{
+		// constraints are reported when Middleware is modified directly
+		c := NewChain()
+		c.AppendNamed("auth", writeMiddleware("A"))
+		c.AppendNamed("rid", writeMiddleware("R"))
+		c.After("auth", "rid")
+		assert.Equal(t, "RAf1", serveRecorder(c.ChainFunc(handlerFunc1)).Body.String())
+
+		c.Middleware = append([]Middleware{writeMiddleware("X")}, c.Middleware...)
+		_, err := c.Resolve()
+		assert.ErrorIs(t, err, ErrMissingDependency)
+		_, err = c.BuildFunc(handlerFunc1)
+		assert.ErrorIs(t, err, ErrMissingDependency)
+		assert.Panics(t, func() { c.ChainFunc(handlerFunc1) })
+	}
+	{
+		// constraints are not checked without declaring them
+		c := &Chain{
+			Middleware: []Middleware{writeMiddleware("a"), nil, writeMiddleware("b")},
+		}
+		assert.NotPanics(t, func() { c.Chain() })
+		order, err := c.resolve()
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 2}, order)
+	}
+}
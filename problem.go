@@ -0,0 +1,277 @@
+package chainist
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Problem is the problem details of HTTP APIs defined in RFC 7807.
+This implements error and has the status code, so it can be returned from the handlers of ErrChain.
+
+    func getUser(w http.ResponseWriter, r *http.Request) error {
+        return chainist.NewProblem(http.StatusNotFound, "user not found").
+            With("user_id", chainist.PathParam(r, "id"))
+    }
+
+This is marshalled into JSON with the extension members flattened.
+
+    {"type":"about:blank","title":"Not Found","status":404,"detail":"user not found","user_id":"1"}
+*/
+type Problem struct {
+	// Type is the URI reference which identifies the problem type.
+	// "about:blank" means the problem has no additional semantics beyond the status code.
+	Type string
+
+	// Title is the short, human-readable summary of the problem type.
+	Title string
+
+	// Status is the HTTP status code.
+	Status int
+
+	// Detail is the human-readable explanation specific to this occurrence of the problem.
+	Detail string
+
+	// Instance is the URI reference which identifies this occurrence of the problem.
+	Instance string
+
+	// Extensions is the extension members.
+	// Members which have the same names as the standard members are ignored.
+	Extensions map[string]any
+}
+
+/*
+NewProblem returns a new problem of the status code with the detail.
+The type is "about:blank" and the title is the status text.
+
+    p := chainist.NewProblem(http.StatusBadRequest, "id must be a number")
+*/
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+/*
+With sets the extension member and returns the problem.
+
+    p := chainist.NewProblem(http.StatusForbidden, "not enough credit").
+        With("balance", 30).
+        With("accounts", []string{"/account/12345"})
+*/
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]any{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error returns the title and the detail of the problem.
+func (p *Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.StatusCode())
+	}
+	if p.Detail == "" {
+		return title
+	}
+	return title + ": " + p.Detail
+}
+
+// StatusCode returns the status code of the problem.
+// 500 Internal Server Error is returned if the status code is not set.
+func (p *Problem) StatusCode() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+
+// problemMembers is the names of the standard members.
+var problemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+// MarshalJSON marshals the problem with the extension members flattened.
+// The standard members are output in the order of RFC 7807
+// and the extension members are output in the order of the names.
+// Empty standard members are omitted.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	member := func(key string, v any) error {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.WriteString(strconv.Quote(key))
+		b.WriteByte(':')
+		b.Write(bs)
+		return nil
+	}
+	standard := []struct {
+		key   string
+		value any
+		empty bool
+	}{
+		{"type", p.Type, p.Type == ""},
+		{"title", p.Title, p.Title == ""},
+		{"status", p.Status, p.Status == 0},
+		{"detail", p.Detail, p.Detail == ""},
+		{"instance", p.Instance, p.Instance == ""},
+	}
+	for _, s := range standard {
+		if s.empty {
+			continue
+		}
+		if err := member(s.key, s.value); err != nil {
+			return nil, err
+		}
+	}
+	keys := make([]string, 0, len(p.Extensions))
+	for k := range p.Extensions {
+		if !problemMembers[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := member(k, p.Extensions[k]); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// UnmarshalJSON unmarshals the problem.
+// Members other than the standard members are stored in Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*p = Problem{}
+	fields := map[string]any{
+		"type":     &p.Type,
+		"title":    &p.Title,
+		"status":   &p.Status,
+		"detail":   &p.Detail,
+		"instance": &p.Instance,
+	}
+	for k, raw := range m {
+		if f, ok := fields[k]; ok {
+			if err := json.Unmarshal(raw, f); err != nil {
+				return err
+			}
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		p.With(k, v)
+	}
+	return nil
+}
+
+/*
+WriteProblem writes the problem as the response.
+The content type is negotiated with the Accept header of the request.
+"application/problem+json" is responded if clients accept JSON or the request is nil,
+and "text/plain" is responded as a fallback.
+
+    chain.AppendGuard(func(w http.ResponseWriter, r *http.Request) bool {
+        if r.Header.Get("Authorization") == "" {
+            chainist.WriteProblem(w, r, chainist.NewProblem(http.StatusUnauthorized, "token is required"))
+            return false
+        }
+        return true
+    })
+*/
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	contentType, body := problemBody(r, p)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.StatusCode())
+	_, _ = w.Write(body)
+}
+
+// problemBody returns the content type and the body of the problem negotiated for the request.
+func problemBody(r *http.Request, p *Problem) (string, []byte) {
+	accept := ""
+	if r != nil {
+		accept = strings.Join(r.Header.Values("Accept"), ",")
+	}
+	if acceptsJSON(accept) {
+		if b, err := json.Marshal(p); err == nil {
+			return "application/problem+json", b
+		}
+	}
+	return "text/plain; charset=utf-8", []byte(p.Error() + "\n")
+}
+
+// acceptsJSON reports whether JSON is preferred to plain text by the Accept header.
+// The quality value of each type is determined by the most specific media range.
+func acceptsJSON(accept string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	qJSON := qualityOf(accept, "application/problem+json", "application/json")
+	qText := qualityOf(accept, "text/plain")
+	return qJSON > 0 && qJSON >= qText
+}
+
+// qualityOf returns the highest quality value of the media types in the Accept header.
+func qualityOf(accept string, types ...string) float64 {
+	best := 0.0
+	for _, t := range types {
+		q, specificity := 0.0, 0
+		for _, rng := range strings.Split(accept, ",") {
+			mt, params, err := mime.ParseMediaType(strings.TrimSpace(rng))
+			if err != nil {
+				continue
+			}
+			s := 0
+			switch {
+			case mt == t:
+				s = 3
+			case strings.HasSuffix(mt, "/*") && strings.HasPrefix(t, mt[:len(mt)-1]):
+				s = 2
+			case mt == "*/*":
+				s = 1
+			}
+			if s <= specificity {
+				continue
+			}
+			specificity = s
+			q = 1
+			if v, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > best {
+			best = q
+		}
+	}
+	return best
+}
@@ -0,0 +1,144 @@
+package chainist
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProblem(t *testing.T) {
+	p := NewProblem(http.StatusNotFound, "user not found")
+	assert.Equal(t, "about:blank", p.Type)
+	assert.Equal(t, "Not Found", p.Title)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, "user not found", p.Detail)
+	assert.Nil(t, p.Extensions)
+
+	p.With("user_id", "1").With("retry", true)
+	assert.Equal(t, map[string]any{"user_id": "1", "retry": true}, p.Extensions)
+}
+
+func TestProblemError(t *testing.T) {
+	{
+		p := NewProblem(http.StatusNotFound, "user not found")
+		assert.Equal(t, "Not Found: user not found", p.Error())
+		assert.Equal(t, http.StatusNotFound, StatusOf(fmt.Errorf("wrap: %w", p)))
+	}
+	{
+		p := &Problem{}
+		assert.Equal(t, "Internal Server Error", p.Error())
+		assert.Equal(t, http.StatusInternalServerError, p.StatusCode())
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	{
+		b, err := json.Marshal(&Problem{})
+		assert.NoError(t, err)
+		assert.Equal(t, `{}`, string(b))
+	}
+	{
+		p := NewProblem(http.StatusForbidden, "not enough credit")
+		p.Instance = "/account/12345/msgs/abc"
+		p.With("balance", 30).With("accounts", []string{"/account/12345"}).With("status", "ignored")
+		b, err := json.Marshal(p)
+		assert.NoError(t, err)
+		e := `{"type":"about:blank","title":"Forbidden","status":403,"detail":"not enough credit",` +
+			`"instance":"/account/12345/msgs/abc","accounts":["/account/12345"],"balance":30}`
+		assert.Equal(t, e, string(b))
+
+		var got Problem
+		assert.NoError(t, json.Unmarshal(b, &got))
+		assert.Equal(t, "Forbidden", got.Title)
+		assert.Equal(t, 403, got.Status)
+		assert.Equal(t, "/account/12345/msgs/abc", got.Instance)
+		assert.Equal(t, map[string]any{"balance": float64(30), "accounts": []any{"/account/12345"}}, got.Extensions)
+	}
+	{
+		_, err := json.Marshal(NewProblem(http.StatusBadRequest, "").With("nan", math.NaN()))
+		assert.Error(t, err)
+	}
+	{
+		var p Problem
+		assert.Error(t, json.Unmarshal([]byte(`[]`), &p))
+		assert.Error(t, json.Unmarshal([]byte(`{"status":"404"}`), &p))
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	p := NewProblem(http.StatusNotFound, "user not found")
+	serve := func(accept ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Length", "10")
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, a := range accept {
+			r.Header.Add("Accept", a)
+		}
+		WriteProblem(w, r, p)
+		return w
+	}
+	{
+		w := serve()
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "", w.Header().Get("Content-Length"))
+		assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"user not found"}`, w.Body.String())
+	}
+	{
+		w := httptest.NewRecorder()
+		WriteProblem(w, nil, p)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	}
+	{
+		w := serve("text/plain")
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "Not Found: user not found\n", w.Body.String())
+	}
+	{
+		// fallback to plain text
+		w := serve("image/png")
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	}
+	{
+		// unmarshallable problems
+		w := httptest.NewRecorder()
+		WriteProblem(w, nil, NewProblem(http.StatusBadRequest, "").With("nan", math.NaN()))
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "Bad Request\n", w.Body.String())
+	}
+}
+
+func TestAcceptsJSON(t *testing.T) {
+	testCases := map[string]bool{
+		"":                                   true,
+		"*/*":                                true,
+		"application/json":                   true,
+		"application/*":                      true,
+		"application/problem+json":           true,
+		"text/html, application/json;q=0.9":  true,
+		"text/plain, */*;q=0.1":              false,
+		"text/*":                             false,
+		"text/plain;q=0.5, application/json": true,
+		"application/problem+json;q=0, application/json;q=0, */*": false,
+		"application/json;q=0.5, text/plain;q=0.5":                true,
+		"invalid;;": false,
+	}
+	for accept, e := range testCases {
+		assert.Equal(t, e, acceptsJSON(accept), accept)
+	}
+}
+
+func TestJSONErrorHandlerProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	h := &JSONErrorHandler{}
+	p := NewProblem(http.StatusConflict, "already exists").With("id", "1")
+	h.HandleError(w, httptest.NewRequest(http.MethodGet, "/", nil), fmt.Errorf("wrap: %w", p))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Conflict","status":409,"detail":"already exists","id":"1"}`, w.Body.String())
+}
@@ -0,0 +1,121 @@
+package chainist
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicReporter reports panics recovered by the middleware created with Recover().
+type PanicReporter interface {
+	// ReportPanic is called with the request, the recovered value and the stack trace
+	// of the goroutine which panicked.
+	ReportPanic(r *http.Request, v any, stack []byte)
+}
+
+// PanicReporterFunc is the function type which implements PanicReporter.
+type PanicReporterFunc func(r *http.Request, v any, stack []byte)
+
+// ReportPanic calls f(r, v, stack).
+func (f PanicReporterFunc) ReportPanic(r *http.Request, v any, stack []byte) {
+	f(r, v, stack)
+}
+
+// logReporter is the default PanicReporter which outputs panics to the standard logger.
+var logReporter = PanicReporterFunc(func(r *http.Request, v any, stack []byte) {
+	log.Printf("chainist: panic serving %s %s: %v\n%s", r.Method, r.URL.Path, v, stack)
+})
+
+/*
+Recover returns a middleware which recovers panics in succeeding middleware and handlers.
+Recovered panics are passed to the reporter with the stack trace.
+If nil is given as the reporter, panics are output to the standard logger.
+
+If the response header has not been sent yet, 500 Internal Server Error is responded
+as a problem with WriteProblem().
+Responses buffered with Buffer() but not sent yet are discarded.
+Otherwise the connection is aborted with http.ErrAbortHandler
+so that clients do not take the partially written response as a complete one.
+http.ErrAbortHandler panicked by handlers is not recovered.
+
+    chain := chainist.NewChain(chainist.Recover(nil))
+
+    // or with a reporter
+    reporter := chainist.PanicReporterFunc(func(r *http.Request, v any, stack []byte) {
+        sentry.CurrentHub().Recover(v)
+    })
+    chain := chainist.NewChain(chainist.Recover(reporter))
+*/
+func Recover(reporter PanicReporter) Middleware {
+	if reporter == nil {
+		reporter = logReporter
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if next == nil {
+				return
+			}
+			w = recordWriter(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				reporter.ReportPanic(r, v, debug.Stack())
+				writePanicResponse(w, r)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writePanicResponse writes 500 Internal Server Error as a problem if possible
+// or aborts the connection.
+func writePanicResponse(w http.ResponseWriter, r *http.Request) {
+	p := NewProblem(http.StatusInternalServerError, "")
+	if b, ok := ResponseBuffer(w); ok && !b.Streaming() {
+		contentType, body := problemBody(r, p)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		b.SetStatus(p.Status)
+		b.SetBody(body)
+		return
+	}
+	if info, _ := ResponseInfo(w); info.Committed() {
+		panic(http.ErrAbortHandler)
+	}
+	WriteProblem(w, r, p)
+}
+
+/*
+EnableRecovery makes the chain recover panics with the middleware created with Recover().
+The middleware is placed at the outermost of the chain, so panics in any middleware
+and the handler function are recovered.
+See Recover() for the reporter.
+
+    chain := chainist.NewChain(handler1, handler2)
+    chain.EnableRecovery(nil)
+*/
+func (c *Chain) EnableRecovery(reporter PanicReporter) *Chain {
+	c, done := c.mutate()
+	defer done()
+	c.recovery = true
+	c.panicReporter = reporter
+	return c
+}
+
+/*
+DisableRecovery disables recovery enabled with EnableRecovery().
+
+    chain.DisableRecovery()
+*/
+func (c *Chain) DisableRecovery() *Chain {
+	c, done := c.mutate()
+	defer done()
+	c.recovery = false
+	c.panicReporter = nil
+	return c
+}
@@ -0,0 +1,143 @@
+package chainist
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func panicHandlerFunc(w http.ResponseWriter, _ *http.Request) {
+	panic("boom")
+}
+
+type testReporter struct {
+	value any
+	stack []byte
+	path  string
+}
+
+func (t *testReporter) ReportPanic(r *http.Request, v any, stack []byte) {
+	t.value = v
+	t.stack = stack
+	t.path = r.URL.Path
+}
+
+func TestPanicReporterFunc(t *testing.T) {
+	var got any
+	f := PanicReporterFunc(func(r *http.Request, v any, stack []byte) {
+		got = v
+	})
+	f.ReportPanic(httptest.NewRequest(http.MethodGet, "/", nil), "v", nil)
+	assert.Equal(t, "v", got)
+}
+
+func TestRecover(t *testing.T) {
+	{
+		w := serveRecorder(Recover(nil)(nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	{
+		w := serveRecorder(Recover(nil)(http.HandlerFunc(handlerFunc1)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "f1", w.Body.String())
+	}
+	{
+		rep := &testReporter{}
+		w := serveRecorder(Recover(rep)(http.HandlerFunc(panicHandlerFunc)))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, w.Body.String())
+		assert.Equal(t, "boom", rep.value)
+		assert.Equal(t, "/", rep.path)
+		assert.Contains(t, string(rep.stack), "panicHandlerFunc")
+	}
+	{
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(os.Stderr)
+		w := serveRecorder(Recover(nil)(http.HandlerFunc(panicHandlerFunc)))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, buf.String(), "chainist: panic serving GET /: boom")
+	}
+	{
+		// headers already sent
+		rep := &testReporter{}
+		h := Recover(rep)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panic("boom")
+		}))
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() { serveRecorder(h) })
+		assert.Equal(t, "boom", rep.value)
+	}
+	{
+		rep := &testReporter{}
+		h := Recover(rep)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() { serveRecorder(h) })
+		assert.Nil(t, rep.value)
+	}
+	{
+		// buffered response is replaced
+		rep := &testReporter{}
+		h := Buffer(0)(Recover(rep)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			if _, err := w.Write([]byte("{}")); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			panic("boom")
+		})))
+		w := serveRecorder(h)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, w.Body.String())
+	}
+	{
+		// plain text for clients which do not accept JSON
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "text/plain")
+		Buffer(0)(Recover(&testReporter{})(http.HandlerFunc(panicHandlerFunc))).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "Internal Server Error\n", w.Body.String())
+	}
+}
+
+func TestEnableRecovery(t *testing.T) {
+	{
+		rep := &testReporter{}
+		c := NewChain().EnableRecovery(rep)
+		assert.True(t, c.recovery)
+		assert.Same(t, rep, c.panicReporter)
+		c.DisableRecovery()
+		assert.False(t, c.recovery)
+		assert.Nil(t, c.panicReporter)
+	}
+	{
+		rep := &testReporter{}
+		c := NewChain().EnableRecovery(rep).EnableBuffering(0)
+		c.AppendPreFunc(handlerFunc1)
+		c.AppendPostFunc(handlerFunc2)
+		w := serveRecorder(c.ChainFunc(panicHandlerFunc))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, w.Body.String())
+		assert.Equal(t, "boom", rep.value)
+	}
+	{
+		// recovery is the outermost, so the reporter gets the request given to the chain
+		var got *http.Request
+		c := NewChain().EnableRecovery(PanicReporterFunc(func(r *http.Request, v any, stack []byte) {
+			got = r
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		c.ChainFunc(panicHandlerFunc).ServeHTTP(httptest.NewRecorder(), r)
+		assert.Same(t, r, got)
+	}
+}
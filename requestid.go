@@ -0,0 +1,149 @@
+package chainist
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestIDHeader is the header used by RequestID() by default.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// RequestIDOptions is the options of RequestID().
+type RequestIDOptions struct {
+	// Header is the header which request IDs are read from and written to.
+	// DefaultRequestIDHeader is used if it is empty.
+	Header string
+
+	// MaxLength is the maximum length of incoming request IDs.
+	// 64 is used if it is 0 or less.
+	MaxLength int
+
+	// Validate reports whether the incoming request ID can be used.
+	// IDs consisting of ASCII letters, digits and "-", "_", ".", ":" are accepted if it is nil.
+	// IDs longer than MaxLength are rejected regardless of this.
+	Validate func(id string) bool
+
+	// Generate generates request IDs for requests without valid IDs.
+	// NewRequestID() is used if it is nil.
+	Generate func() string
+}
+
+type requestIDKey struct{}
+
+// requestIDValue shares the request ID with the preceding middleware in the chain, such as AccessLog().
+var requestIDValue = NewKey[string]("request id")
+
+/*
+RequestID returns a middleware which propagates request IDs.
+The request ID is read from the request header, and a new one is generated if it is absent or invalid.
+The request ID is stored in the request context, which can be obtained with RequestIDFrom(),
+and written to the response header.
+The request ID is also shared with the preceding middleware in the same chain through the store of request-scoped values.
+See Key.
+If nil is given as the options, the defaults are used.
+
+    chain := chainist.NewChain(chainist.RequestID(nil))
+    chain.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+        log.Println(chainist.RequestIDFrom(r.Context()))
+    })
+
+    // or with options
+    chain := chainist.NewChain(chainist.RequestID(&chainist.RequestIDOptions{
+        Header: "X-Correlation-ID",
+    }))
+*/
+func RequestID(opts *RequestIDOptions) Middleware {
+	if opts == nil {
+		opts = &RequestIDOptions{}
+	}
+	header := opts.Header
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = 64
+	}
+	validate := opts.Validate
+	if validate == nil {
+		validate = validRequestID
+	}
+	generate := opts.Generate
+	if generate == nil {
+		generate = NewRequestID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if id == "" || len(id) > maxLength || !validate(id) {
+				id = generate()
+			}
+			w.Header().Set(header, id)
+			r = requestIDValue.Set(r, id)
+			if next != nil {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+			}
+		})
+	}
+}
+
+/*
+RequestIDFrom returns the request ID stored in the context by the middleware of RequestID().
+The middleware placed before RequestID() in the same chain can also get the request ID
+after the succeeding handlers returned.
+Empty string is returned if not found.
+
+    id := chainist.RequestIDFrom(r.Context())
+*/
+func RequestIDFrom(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	id, _ := requestIDValue.Value(ctx)
+	return id
+}
+
+// validRequestID reports whether the request ID consists of
+// ASCII letters, digits and "-", "_", ".", ":".
+func validRequestID(id string) bool {
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+/*
+NewRequestID returns a new request ID which is a UUID version 7 defined in RFC 9562,
+e.g. "01890a5d-ac96-774b-bcce-b302099a8057".
+The IDs are sortable by the time of the generation in milliseconds.
+*/
+func NewRequestID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		u[i] = byte(ms >> (40 - 8*i))
+	}
+	u[6] = u[6]&0x0f | 0x70 // version 7
+	u[8] = u[8]&0x3f | 0x80 // variant 10
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
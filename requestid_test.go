@@ -0,0 +1,133 @@
+package chainist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidv7 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// requestIDHandlerFunc writes the request ID in the context.
+func requestIDHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(RequestIDFrom(r.Context())))
+}
+
+func TestRequestID(t *testing.T) {
+	h := NewChain(RequestID(nil)).ChainFunc(requestIDHandlerFunc)
+	serve := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			r.Header.Set("X-Request-ID", id)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	{
+		w := serve("abc-123_4.5:6")
+		assert.Equal(t, "abc-123_4.5:6", w.Body.String())
+		assert.Equal(t, "abc-123_4.5:6", w.Header().Get("X-Request-ID"))
+	}
+	{
+		w := serve("")
+		assert.Regexp(t, uuidv7, w.Body.String())
+		assert.Equal(t, w.Body.String(), w.Header().Get("X-Request-ID"))
+	}
+	// invalid IDs are replaced
+	for _, id := range []string{"has space", "<script>", "ünicode", strings.Repeat("a", 65)} {
+		w := serve(id)
+		assert.Regexp(t, uuidv7, w.Body.String(), id)
+	}
+	assert.Equal(t, strings.Repeat("a", 64), serve(strings.Repeat("a", 64)).Body.String())
+}
+
+func TestRequestIDOptions(t *testing.T) {
+	opts := &RequestIDOptions{
+		Header:    "X-Correlation-ID",
+		MaxLength: 8,
+		Validate: func(id string) bool {
+			return strings.HasPrefix(id, "id")
+		},
+		Generate: func() string {
+			return "generated"
+		},
+	}
+	h := NewChain(RequestID(opts)).ChainFunc(requestIDHandlerFunc)
+	for id, want := range map[string]string{
+		"id1":       "id1",
+		"x1":        "generated",
+		"id3456789": "generated",
+		"":          "generated",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Correlation-ID", id)
+		r.Header.Set("X-Request-ID", "ignored")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, want, w.Body.String(), id)
+		assert.Equal(t, want, w.Header().Get("X-Correlation-ID"))
+		assert.Equal(t, "", w.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestRequestIDFrom(t *testing.T) {
+	assert.Equal(t, "", RequestIDFrom(context.Background()))
+	{
+		// available in the post functions
+		var id string
+		c := NewChain(RequestID(nil)).AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = RequestIDFrom(r.Context())
+		})
+		w := serveRecorder(c.ChainFunc(handlerFunc1))
+		assert.Equal(t, w.Header().Get("X-Request-ID"), id)
+		assert.NotEqual(t, "", id)
+	}
+	{
+		// recorded in access logs regardless of the order
+		var recs []*AccessRecord
+		sink := captureSink(&recs)
+		serveRecorder(NewChain(RequestID(nil), AccessLog(&AccessLogOptions{Sink: sink})).ChainFunc(handlerFunc1))
+		serveRecorder(NewChain(AccessLog(&AccessLogOptions{Sink: sink}), RequestID(nil)).ChainFunc(handlerFunc1))
+		assert.Regexp(t, uuidv7, recs[0].RequestID)
+		assert.Regexp(t, uuidv7, recs[1].RequestID)
+
+		// with the custom header
+		opts := &RequestIDOptions{Header: "X-Correlation-ID"}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Correlation-ID", "corr-1")
+		NewChain(AccessLog(&AccessLogOptions{Sink: sink}), RequestID(opts)).ChainFunc(handlerFunc1).ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, "corr-1", recs[2].RequestID)
+
+		// IDs in the request header are not trusted without RequestID()
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Request-ID", "untrusted")
+		NewChain(AccessLog(&AccessLogOptions{Sink: sink})).ChainFunc(handlerFunc1).ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, "", recs[3].RequestID)
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	seen := map[string]bool{}
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		id := NewRequestID()
+		assert.Regexp(t, uuidv7, id)
+		assert.False(t, seen[id])
+		seen[id] = true
+		// the timestamp part is not decreasing
+		assert.True(t, id[:13] >= prev)
+		prev = id[:13]
+	}
+	// the timestamp is the unix time in milliseconds
+	ms, err := strconv.ParseInt(strings.ReplaceAll(NewRequestID()[:13], "-", ""), 16, 64)
+	assert.Nil(t, err)
+	assert.InDelta(t, time.Now().UnixMilli(), ms, 1000)
+}
@@ -0,0 +1,251 @@
+package chainist
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseRecord holds what has been written to a http response.
+// This can be obtained with ResponseInfo() in the middleware and handler functions of a chain.
+type ResponseRecord struct {
+	// Status is the status code of the response.
+	// This is 0 if the header is not written yet.
+	Status int
+
+	// Bytes is the number of bytes written to the response body.
+	Bytes int64
+
+	// Header is the snapshot of the response header
+	// taken when the header was written.
+	// This is nil if the header is not written yet.
+	Header http.Header
+
+	// FirstWrite is the time when the header or the body was written first.
+	FirstWrite time.Time
+
+	// Hijacked reports whether the connection was hijacked.
+	Hijacked bool
+}
+
+// Committed reports whether the response header has been written.
+// Header and status code can not be changed once committed.
+func (r ResponseRecord) Committed() bool {
+	return r.Status != 0 || r.Hijacked
+}
+
+/*
+ResponseInfo returns what has been written to the response through the given writer.
+The writers given to the middleware and the handler functions chained with Chain() or ChainFunc()
+and to the post-executable functions are recorded.
+false is returned if the writer is not recorded.
+
+Writers wrapped by other middleware can also be used as far as they implement `Unwrap() http.ResponseWriter`.
+
+    chain.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+        info, ok := chainist.ResponseInfo(w)
+        if ok {
+            log.Println(info.Status, info.Bytes)
+        }
+    })
+*/
+func ResponseInfo(w http.ResponseWriter) (ResponseRecord, bool) {
+	rw := findRecorder(w)
+	if rw == nil {
+		return ResponseRecord{}, false
+	}
+	return rw.record, true
+}
+
+// recorder is implemented by the writers which record responses.
+type recorder interface {
+	recorder() *responseWriter
+}
+
+// findRecorder finds the recording writer by unwrapping the given writer.
+// nil is returned if not found.
+// Writers beyond buffered responses are not searched because
+// what is written to the buffer is not written to the underlying writer as it is.
+func findRecorder(w http.ResponseWriter) *responseWriter {
+	for w != nil {
+		if rw, ok := w.(recorder); ok {
+			return rw.recorder()
+		}
+		if _, ok := w.(interface{ buffered() *BufferedResponse }); ok {
+			return nil
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
+
+// findWriter finds the writer which implements T by unwrapping the given writer.
+func findWriter[T any](w http.ResponseWriter) (T, bool) {
+	for w != nil {
+		if t, ok := w.(T); ok {
+			return t, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// recordWriter returns the writer which records the response written to w.
+// w is returned as it is if it already records responses.
+// The returned writer implements http.Flusher and io.ReaderFrom,
+// and implements http.Hijacker and http.Pusher only when w implements them.
+// Flush is delegated to w with http.ResponseController, so it follows Unwrap() of w.
+func recordWriter(w http.ResponseWriter) http.ResponseWriter {
+	if findRecorder(w) != nil {
+		return w
+	}
+	rw := &responseWriter{ResponseWriter: w}
+	_, hijacker := w.(http.Hijacker)
+	_, pusher := w.(http.Pusher)
+	switch {
+	case hijacker && pusher:
+		return &hijackPushWriter{rw}
+	case hijacker:
+		return &hijackWriter{rw}
+	case pusher:
+		return &pushWriter{rw}
+	default:
+		return rw
+	}
+}
+
+// recordMiddleware makes the response written by the next handler recorded.
+func recordMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(recordWriter(w), r)
+	})
+}
+
+// responseWriter is the http.ResponseWriter which records the response.
+type responseWriter struct {
+	http.ResponseWriter
+	record ResponseRecord
+}
+
+func (w *responseWriter) recorder() *responseWriter {
+	return w
+}
+
+// commit records the status code and the snapshot of the header.
+func (w *responseWriter) commit(code int) {
+	if w.record.Status != 0 {
+		return
+	}
+	w.record.Status = code
+	w.record.Header = w.ResponseWriter.Header().Clone()
+	if w.record.FirstWrite.IsZero() {
+		w.record.FirstWrite = time.Now()
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// informational responses except for 101 Switching Protocols
+	// can be written multiple times before the final header.
+	if code < 200 && code != http.StatusSwitchingProtocols {
+		if w.record.FirstWrite.IsZero() {
+			w.record.FirstWrite = time.Now()
+		}
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.commit(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.commit(http.StatusOK)
+	n, err := w.ResponseWriter.Write(b)
+	w.record.Bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.commit(http.StatusOK)
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		// hide ReadFrom of this writer to avoid infinite recursion
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, src)
+	}
+	w.record.Bytes += n
+	return n, err
+}
+
+// Flush flushes the underlying writer. See FlushError().
+func (w *responseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError flushes the underlying writer with http.ResponseController,
+// so that writers which can be flushed only through Unwrap() are also flushed.
+// http.ErrNotSupported is returned if the underlying writer can not be flushed.
+func (w *responseWriter) FlushError() error {
+	err := http.NewResponseController(w.ResponseWriter).Flush()
+	if !errors.Is(err, http.ErrNotSupported) {
+		w.commit(http.StatusOK)
+	}
+	return err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		w.record.Hijacked = true
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) push(target string, opts *http.PushOptions) error {
+	return w.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+type hijackWriter struct {
+	*responseWriter
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+type pushWriter struct {
+	*responseWriter
+}
+
+func (w *pushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type hijackPushWriter struct {
+	*responseWriter
+}
+
+func (w *hijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w *hijackPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
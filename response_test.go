@@ -0,0 +1,259 @@
+package chainist
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fullWriter implements all optional interfaces of http.ResponseWriter.
+type fullWriter struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+	pushed   string
+	readFrom bool
+}
+
+func (w *fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+func (w *fullWriter) Push(target string, _ *http.PushOptions) error {
+	w.pushed = target
+	return nil
+}
+
+func (w *fullWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.ResponseRecorder, src)
+}
+
+// hijackOnlyWriter implements http.Hijacker but not http.Pusher.
+type hijackOnlyWriter struct {
+	http.ResponseWriter
+}
+
+func (w *hijackOnlyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("hijack failed")
+}
+
+// pushOnlyWriter implements http.Pusher but not http.Hijacker.
+type pushOnlyWriter struct {
+	http.ResponseWriter
+}
+
+func (w *pushOnlyWriter) Push(string, *http.PushOptions) error {
+	return http.ErrNotSupported
+}
+
+// headerOnlyWriter records status codes given to WriteHeader.
+type headerOnlyWriter struct {
+	header http.Header
+	codes  []int
+}
+
+func (w *headerOnlyWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *headerOnlyWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *headerOnlyWriter) WriteHeader(code int) {
+	w.codes = append(w.codes, code)
+}
+
+// wrappingWriter is a writer of third party middleware which supports unwrapping.
+type wrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestResponseInfo(t *testing.T) {
+	{
+		info, ok := ResponseInfo(httptest.NewRecorder())
+		assert.False(t, ok)
+		assert.False(t, info.Committed())
+	}
+	{
+		info, ok := ResponseInfo(nil)
+		assert.False(t, ok)
+		assert.Equal(t, ResponseRecord{}, info)
+	}
+	{
+		w := recordWriter(httptest.NewRecorder())
+		info, ok := ResponseInfo(w)
+		assert.True(t, ok)
+		assert.False(t, info.Committed())
+		assert.Equal(t, 0, info.Status)
+		assert.Nil(t, info.Header)
+		assert.True(t, info.FirstWrite.IsZero())
+	}
+	{
+		// informational responses are passed through without committing
+		w := recordWriter(&headerOnlyWriter{header: http.Header{}})
+		w.WriteHeader(http.StatusEarlyHints)
+		info, _ := ResponseInfo(w)
+		assert.False(t, info.Committed())
+		assert.False(t, info.FirstWrite.IsZero())
+		assert.Equal(t, []int{http.StatusEarlyHints}, findRecorder(w).ResponseWriter.(*headerOnlyWriter).codes)
+	}
+	{
+		w := recordWriter(httptest.NewRecorder())
+		w.Header().Set("X-Test", "before")
+		w.WriteHeader(http.StatusCreated)
+		w.Header().Set("X-Test", "after")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("hello"))
+		assert.NoError(t, err)
+		_, err = w.Write([]byte("world"))
+		assert.NoError(t, err)
+
+		info, _ := ResponseInfo(&wrappingWriter{w})
+		assert.True(t, info.Committed())
+		assert.Equal(t, http.StatusCreated, info.Status)
+		assert.Equal(t, int64(10), info.Bytes)
+		assert.Equal(t, "before", info.Header.Get("X-Test"))
+	}
+	{
+		w := recordWriter(httptest.NewRecorder())
+		_, err := w.Write([]byte("hello"))
+		assert.NoError(t, err)
+		info, _ := ResponseInfo(w)
+		assert.Equal(t, http.StatusOK, info.Status)
+		assert.Equal(t, int64(5), info.Bytes)
+	}
+}
+
+func TestRecordWriter(t *testing.T) {
+	{
+		w := recordWriter(httptest.NewRecorder())
+		assert.Same(t, w, recordWriter(w))
+		assert.Same(t, w, recordWriter(&wrappingWriter{w}).(*wrappingWriter).ResponseWriter)
+	}
+	{
+		rec := httptest.NewRecorder()
+		w := recordWriter(rec)
+		_, isHijacker := w.(http.Hijacker)
+		_, isPusher := w.(http.Pusher)
+		assert.False(t, isHijacker)
+		assert.False(t, isPusher)
+
+		n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+		w.(http.Flusher).Flush()
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, "hello", rec.Body.String())
+		assert.Same(t, rec, w.(interface{ Unwrap() http.ResponseWriter }).Unwrap())
+
+		info, _ := ResponseInfo(w)
+		assert.Equal(t, http.StatusOK, info.Status)
+		assert.Equal(t, int64(5), info.Bytes)
+	}
+	{
+		fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+		w := recordWriter(fw)
+
+		n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+		assert.True(t, fw.readFrom)
+
+		assert.NoError(t, w.(http.Pusher).Push("/style.css", nil))
+		assert.Equal(t, "/style.css", fw.pushed)
+
+		_, _, err = w.(http.Hijacker).Hijack()
+		assert.NoError(t, err)
+		assert.True(t, fw.hijacked)
+
+		info, _ := ResponseInfo(w)
+		assert.True(t, info.Hijacked)
+		assert.Equal(t, int64(5), info.Bytes)
+	}
+	{
+		w := recordWriter(&hijackOnlyWriter{httptest.NewRecorder()})
+		_, isPusher := w.(http.Pusher)
+		assert.False(t, isPusher)
+		_, _, err := w.(http.Hijacker).Hijack()
+		assert.Error(t, err)
+		info, _ := ResponseInfo(w)
+		assert.False(t, info.Hijacked)
+	}
+	{
+		w := recordWriter(&pushOnlyWriter{httptest.NewRecorder()})
+		_, isHijacker := w.(http.Hijacker)
+		assert.False(t, isHijacker)
+		assert.ErrorIs(t, w.(http.Pusher).Push("/", nil), http.ErrNotSupported)
+	}
+	{
+		// Flush is ignored if the underlying writer is not a http.Flusher.
+		w := recordWriter(&pushOnlyWriter{httptest.NewRecorder()})
+		w.(http.Flusher).Flush()
+		info, _ := ResponseInfo(w)
+		assert.False(t, info.Committed())
+		assert.ErrorIs(t, http.NewResponseController(w).Flush(), http.ErrNotSupported)
+	}
+	{
+		// Flush follows Unwrap() of the underlying writer.
+		rec := httptest.NewRecorder()
+		w := recordWriter(&wrappingWriter{rec})
+		assert.NoError(t, http.NewResponseController(w).Flush())
+		assert.True(t, rec.Flushed)
+		info, _ := ResponseInfo(w)
+		assert.Equal(t, http.StatusOK, info.Status)
+	}
+}
+
+func TestResponseInfoInChain(t *testing.T) {
+	var info ResponseRecord
+	c := NewChain()
+	c.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ = ResponseInfo(w)
+	})
+	c.SetHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusTeapot)
+		if _, err := w.Write([]byte("teapot")); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	s := httptest.NewServer(c.Chain())
+	defer s.Close()
+
+	res, err := http.Get(s.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	assert.Equal(t, http.StatusTeapot, info.Status)
+	assert.Equal(t, int64(6), info.Bytes)
+	assert.Equal(t, "text/plain", info.Header.Get("Content-Type"))
+	assert.False(t, info.FirstWrite.IsZero())
+}
+
+func TestResponseInfoInPostMiddleware(t *testing.T) {
+	var info ResponseRecord
+	h := &HandlerFuncWrapper{
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			info, _ = ResponseInfo(w)
+		},
+	}
+	w := httptest.NewRecorder()
+	h.PostMiddleware(http.HandlerFunc(handlerFunc1)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, info.Status)
+	assert.Equal(t, int64(2), info.Bytes)
+}
@@ -0,0 +1,662 @@
+package chainist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+Router is a http.Handler which dispatches requests to handler functions by the method and the path.
+Routes are registered with patterns which may contain path parameters and a trailing wildcard.
+
+  - "/users" matches only "/users".
+  - "/users/{id}" matches "/users/1" but neither "/users" nor "/users/1/posts".
+  - "/files/{path...}" matches "/files/", "/files/a" and "/files/a/b".
+
+Static segments take precedence over parameters, and parameters take precedence over wildcards.
+Values of the parameters can be obtained with PathParam().
+
+Middleware wrapping the router, such as AccessLog(), observes the pattern of the matched route with RoutePattern()
+after the router returned. If the router is nested in a route of another router or a mux,
+the nested router keeps its result to itself, and middleware wrapping the outer one observes the pattern of the outer one.
+
+The router has its own chain, and every group and route has its own chain too.
+The chain of a route is composed on top of the chain of its group,
+which is composed on top of the chain of the parent group, and so on up to the chain of the router.
+Routes are compiled when the router serves the first request,
+so routes and middleware must be registered before that.
+
+    router := chainist.NewRouter(loggingHandler)
+    router.Get("/healthz", healthzHandlerFunc)
+
+    api := router.Group("/api", authHandler)
+    api.Get("/users/{id}", userHandlerFunc)
+    api.Post("/users", createUserHandlerFunc).Use(csrfHandler)
+
+    http.ListenAndServe(":8080", router)
+*/
+type Router struct {
+	// NotFound is the handler called when no routes match the path.
+	// http.NotFound is used if nil.
+	NotFound http.Handler
+
+	// MethodNotAllowed is the handler called when routes match the path but not the method.
+	// The Allow header is set before calling the handler.
+	// 405 Method Not Allowed is responded if nil.
+	MethodNotAllowed http.Handler
+
+	root *Group
+
+	mu       sync.Mutex
+	routes   []*Route
+	keys     map[string]string
+	compiled bool
+
+	once             sync.Once
+	tree             *routeNode
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	err              error
+}
+
+/*
+NewRouter returns a new router.
+The given middleware is used as the chain of the router, which is applied to all routes.
+Nil middleware is ignored.
+
+    router := chainist.NewRouter(handler1, handler2)
+*/
+func NewRouter(ms ...Middleware) *Router {
+	rt := &Router{keys: map[string]string{}}
+	rt.root = &Group{router: rt, chain: NewChain().Extend(ms...)}
+	return rt
+}
+
+// Chain returns the chain of the router. See Group.Chain().
+func (rt *Router) Chain() *Chain {
+	return rt.root.Chain()
+}
+
+// Use appends middleware to the chain of the router. See Group.Use().
+func (rt *Router) Use(ms ...Middleware) *Router {
+	rt.root.Use(ms...)
+	return rt
+}
+
+// Group returns a new route group. See Group.Group().
+func (rt *Router) Group(prefix string, ms ...Middleware) *Group {
+	return rt.root.Group(prefix, ms...)
+}
+
+// Handle registers a route. See Group.Handle().
+func (rt *Router) Handle(method string, pattern string, f http.HandlerFunc) *Route {
+	return rt.root.Handle(method, pattern, f)
+}
+
+// Get registers a route for GET requests. See Group.Handle().
+func (rt *Router) Get(pattern string, f http.HandlerFunc) *Route {
+	return rt.root.Get(pattern, f)
+}
+
+// Post registers a route for POST requests. See Group.Handle().
+func (rt *Router) Post(pattern string, f http.HandlerFunc) *Route {
+	return rt.root.Post(pattern, f)
+}
+
+// Put registers a route for PUT requests. See Group.Handle().
+func (rt *Router) Put(pattern string, f http.HandlerFunc) *Route {
+	return rt.root.Put(pattern, f)
+}
+
+// Patch registers a route for PATCH requests. See Group.Handle().
+func (rt *Router) Patch(pattern string, f http.HandlerFunc) *Route {
+	return rt.root.Patch(pattern, f)
+}
+
+// Delete registers a route for DELETE requests. See Group.Handle().
+func (rt *Router) Delete(pattern string, f http.HandlerFunc) *Route {
+	return rt.root.Delete(pattern, f)
+}
+
+/*
+Build compiles the routes and returns the router as a http.Handler.
+Misconfiguration of the chains, e.g. unsatisfiable ordering constraints, is reported as an error.
+Without calling this, routes are compiled at the first request and the router panics on misconfiguration.
+
+    handler, err := router.Build()
+    if err != nil {
+        panic(err)
+    }
+    http.ListenAndServe(":8080", handler)
+*/
+func (rt *Router) Build() (http.Handler, error) {
+	rt.once.Do(rt.compile)
+	if rt.err != nil {
+		return nil, rt.err
+	}
+	return rt, nil
+}
+
+// ServeHTTP dispatches the request to the handler of the matched route.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.once.Do(rt.compile)
+	if rt.err != nil {
+		panic(rt.err)
+	}
+
+	// the escaped path is split so that encoded slashes, e.g. "%2F", are kept in segments
+	// as http.ServeMux does. See routeNode.match().
+	var segs []string
+	if path := r.URL.EscapedPath(); strings.HasPrefix(path, "/") {
+		segs = strings.Split(path[1:], "/")
+	}
+	allow := map[string]bool{}
+	var route *compiledRoute
+	var values []string
+	if segs != nil {
+		route, values = rt.tree.match(r.Method, segs, nil, allow)
+	}
+
+	r, m := withRouterMatch(r)
+
+	if route == nil {
+		m.pattern, m.names, m.values = "", nil, nil
+		if len(allow) == 0 {
+			rt.notFound.ServeHTTP(w, r)
+			return
+		}
+		methods := make([]string, 0, len(allow))
+		for method := range allow {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		rt.methodNotAllowed.ServeHTTP(w, r)
+		return
+	}
+
+	m.pattern, m.names, m.values = route.pattern, route.names, values
+	route.handler.ServeHTTP(w, r)
+}
+
+// compile builds the routing tree and composes the chains of the routes.
+func (rt *Router) compile() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.compiled = true
+
+	rt.tree = &routeNode{}
+	for _, route := range rt.routes {
+		h, err := route.build()
+		if err != nil {
+			rt.err = fmt.Errorf("%s %s: %w", route.method, route.pattern, err)
+			return
+		}
+		rt.tree.add(route, h)
+	}
+
+	notFound := rt.NotFound
+	if notFound == nil {
+		notFound = http.HandlerFunc(http.NotFound)
+	}
+	methodNotAllowed := rt.MethodNotAllowed
+	if methodNotAllowed == nil {
+		methodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		})
+	}
+	// the chain of the router is also applied to the fallback handlers
+	// so that logging and recovery work for unmatched requests
+	if rt.notFound, rt.err = rt.root.chain.BuildFunc(notFound.ServeHTTP); rt.err != nil {
+		return
+	}
+	rt.methodNotAllowed, rt.err = rt.root.chain.BuildFunc(methodNotAllowed.ServeHTTP)
+}
+
+// register adds the route to the router.
+// This panics if the pattern is invalid or conflicts with registered routes.
+func (rt *Router) register(route *Route) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.compiled {
+		panic(fmt.Errorf("%w: %s %s is registered after the router started serving", ErrInvalidRoute, route.method, route.pattern))
+	}
+	segs, err := parsePattern(route.pattern)
+	if err != nil {
+		panic(err)
+	}
+	route.segments = segs
+	key := route.method + " " + routeKeyOf(segs)
+	if p, ok := rt.keys[key]; ok {
+		panic(fmt.Errorf("%w: %s %s conflicts with %s %s", ErrInvalidRoute, route.method, route.pattern, route.method, p))
+	}
+	rt.keys[key] = route.pattern
+	rt.routes = append(rt.routes, route)
+}
+
+/*
+Group is a group of routes which share a path prefix and a chain.
+Groups are created with Router.Group() or Group.Group().
+*/
+type Group struct {
+	router *Router
+	parent *Group
+	prefix string
+	chain  *Chain
+}
+
+/*
+Chain returns the chain of the group.
+Middleware in the chain is applied to all routes in the group and its sub groups.
+The chain can be modified directly, but the handler function of the chain is not used.
+
+    api := router.Group("/api")
+    api.Chain().AppendNamed("auth", authHandler)
+*/
+func (g *Group) Chain() *Chain {
+	return g.chain
+}
+
+/*
+Use appends middleware to the chain of the group.
+Nil middleware is ignored.
+
+    api := router.Group("/api")
+    api.Use(authHandler, csrfHandler)
+*/
+func (g *Group) Use(ms ...Middleware) *Group {
+	g.chain.Extend(ms...)
+	return g
+}
+
+/*
+Group returns a new sub group which has the given path prefix.
+The prefix is appended to the prefix of the group, and may contain path parameters.
+The given middleware is used as the chain of the sub group,
+which is composed on top of the chain of the group.
+
+    api := router.Group("/api", authHandler)
+    users := api.Group("/users/{id}")
+
+    // handles "/api/users/{id}/posts"
+    users.Get("/posts", postsHandlerFunc)
+*/
+func (g *Group) Group(prefix string, ms ...Middleware) *Group {
+	return &Group{
+		router: g.router,
+		parent: g,
+		prefix: g.prefix + strings.TrimSuffix(prefix, "/"),
+		chain:  NewChain().Extend(ms...),
+	}
+}
+
+/*
+Handle registers a route which calls the handler function for the method and the pattern.
+The pattern is appended to the prefix of the group. See Router for the syntax of patterns.
+An empty method matches any methods. GET routes also match HEAD requests unless HEAD routes are registered.
+This panics if the handler function is nil, the pattern is invalid,
+or the same method and pattern is already registered.
+
+    router.Handle(http.MethodGet, "/users/{id}", userHandlerFunc)
+
+    // any methods
+    router.Handle("", "/proxy/{path...}", proxyHandlerFunc)
+*/
+func (g *Group) Handle(method string, pattern string, f http.HandlerFunc) *Route {
+	if f == nil {
+		panic(fmt.Errorf("%w: nil handler function for %s %s", ErrInvalidRoute, method, pattern))
+	}
+	route := &Route{
+		method:  method,
+		pattern: g.prefix + pattern,
+		handler: f,
+		group:   g,
+		chain:   NewChain(),
+	}
+	g.router.register(route)
+	return route
+}
+
+// Get registers a route for GET requests. See Handle().
+func (g *Group) Get(pattern string, f http.HandlerFunc) *Route {
+	return g.Handle(http.MethodGet, pattern, f)
+}
+
+// Post registers a route for POST requests. See Handle().
+func (g *Group) Post(pattern string, f http.HandlerFunc) *Route {
+	return g.Handle(http.MethodPost, pattern, f)
+}
+
+// Put registers a route for PUT requests. See Handle().
+func (g *Group) Put(pattern string, f http.HandlerFunc) *Route {
+	return g.Handle(http.MethodPut, pattern, f)
+}
+
+// Patch registers a route for PATCH requests. See Handle().
+func (g *Group) Patch(pattern string, f http.HandlerFunc) *Route {
+	return g.Handle(http.MethodPatch, pattern, f)
+}
+
+// Delete registers a route for DELETE requests. See Handle().
+func (g *Group) Delete(pattern string, f http.HandlerFunc) *Route {
+	return g.Handle(http.MethodDelete, pattern, f)
+}
+
+// Route is a route registered to a router.
+type Route struct {
+	method   string
+	pattern  string
+	segments []segment
+	handler  http.HandlerFunc
+	group    *Group
+	chain    *Chain
+}
+
+// Method returns the method of the route. Empty string means any methods.
+func (r *Route) Method() string {
+	return r.method
+}
+
+// Pattern returns the pattern of the route including the prefix of the group.
+func (r *Route) Pattern() string {
+	return r.pattern
+}
+
+/*
+Chain returns the chain of the route.
+Middleware in the chain is applied only to the route,
+and the chain is composed on top of the chain of the group.
+The handler function of the chain is not used.
+
+    route := router.Post("/users", createUserHandlerFunc)
+    route.Chain().AppendGuard(adminGuard)
+*/
+func (r *Route) Chain() *Chain {
+	return r.chain
+}
+
+/*
+Use appends middleware to the chain of the route.
+Nil middleware is ignored.
+
+    router.Post("/users", createUserHandlerFunc).Use(csrfHandler)
+*/
+func (r *Route) Use(ms ...Middleware) *Route {
+	r.chain.Extend(ms...)
+	return r
+}
+
+// build composes the chains of the route and its groups with the handler function.
+// The chain of the route is the innermost and the chain of the router is the outermost.
+func (r *Route) build() (http.Handler, error) {
+	h, err := r.chain.BuildFunc(r.handler)
+	if err != nil {
+		return nil, err
+	}
+	for g := r.group; g != nil; g = g.parent {
+		if h, err = g.chain.BuildFunc(h.ServeHTTP); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// segment is a path segment of route patterns.
+type segment struct {
+	value    string // literal value, or name for parameters
+	param    bool
+	wildcard bool
+}
+
+// parsePattern parses the route pattern into segments.
+func parsePattern(pattern string) ([]segment, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("%w: pattern %q must start with \"/\"", ErrInvalidRoute, pattern)
+	}
+	parts := strings.Split(pattern[1:], "/")
+	segs := make([]segment, 0, len(parts))
+	seen := map[string]bool{}
+	for i, p := range parts {
+		if !strings.HasPrefix(p, "{") || !strings.HasSuffix(p, "}") {
+			if strings.ContainsAny(p, "{}") {
+				return nil, fmt.Errorf("%w: pattern %q has a parameter which is not a whole segment", ErrInvalidRoute, pattern)
+			}
+			segs = append(segs, segment{value: p})
+			continue
+		}
+		s := segment{value: p[1 : len(p)-1], param: true}
+		if strings.HasSuffix(s.value, "...") {
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("%w: pattern %q has a wildcard which is not at the end", ErrInvalidRoute, pattern)
+			}
+			s.value = strings.TrimSuffix(s.value, "...")
+			s.param, s.wildcard = false, true
+		}
+		if !validParamName(s.value) {
+			return nil, fmt.Errorf("%w: pattern %q has an invalid parameter name %q", ErrInvalidRoute, pattern, s.value)
+		}
+		if seen[s.value] {
+			return nil, fmt.Errorf("%w: pattern %q has a duplicate parameter name %q", ErrInvalidRoute, pattern, s.value)
+		}
+		seen[s.value] = true
+		segs = append(segs, s)
+	}
+	return segs, nil
+}
+
+// validParamName reports whether the name consists of letters, digits and underscores.
+func validParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// routeKeyOf returns the pattern without parameter names,
+// which is used to detect conflicting routes.
+func routeKeyOf(segs []segment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		b.WriteString("/")
+		switch {
+		case s.param:
+			b.WriteString("{}")
+		case s.wildcard:
+			b.WriteString("{...}")
+		default:
+			b.WriteString(s.value)
+		}
+	}
+	return b.String()
+}
+
+// routeNode is a node of the routing tree.
+type routeNode struct {
+	static   map[string]*routeNode
+	param    *routeNode
+	wildcard *routeNode
+	routes   map[string]*compiledRoute
+}
+
+// compiledRoute is a route whose chains are composed.
+type compiledRoute struct {
+	pattern string
+	names   []string
+	handler http.Handler
+}
+
+// add adds the route to the tree.
+func (n *routeNode) add(route *Route, h http.Handler) {
+	var names []string
+	for _, s := range route.segments {
+		switch {
+		case s.param:
+			if n.param == nil {
+				n.param = &routeNode{}
+			}
+			n = n.param
+			names = append(names, s.value)
+		case s.wildcard:
+			if n.wildcard == nil {
+				n.wildcard = &routeNode{}
+			}
+			n = n.wildcard
+			names = append(names, s.value)
+		default:
+			if n.static == nil {
+				n.static = map[string]*routeNode{}
+			}
+			if n.static[s.value] == nil {
+				n.static[s.value] = &routeNode{}
+			}
+			n = n.static[s.value]
+		}
+	}
+	if n.routes == nil {
+		n.routes = map[string]*compiledRoute{}
+	}
+	n.routes[route.method] = &compiledRoute{pattern: route.pattern, names: names, handler: h}
+}
+
+// match finds the route which matches the method and the escaped path segments.
+// Segments are unescaped when they are compared with static segments or used as values.
+// Methods of the routes which match the path but not the method are added to allow.
+func (n *routeNode) match(method string, segs []string, values []string, allow map[string]bool) (*compiledRoute, []string) {
+	if len(segs) == 0 {
+		if route := n.lookup(method, allow); route != nil {
+			return route, values
+		}
+		return nil, nil
+	}
+	seg := unescapeSegment(segs[0])
+	if c := n.static[seg]; c != nil {
+		if route, vs := c.match(method, segs[1:], values, allow); route != nil {
+			return route, vs
+		}
+	}
+	if n.param != nil && segs[0] != "" {
+		if route, vs := n.param.match(method, segs[1:], append(values, seg), allow); route != nil {
+			return route, vs
+		}
+	}
+	if n.wildcard != nil {
+		if route := n.wildcard.lookup(method, allow); route != nil {
+			return route, append(values, unescapeSegment(strings.Join(segs, "/")))
+		}
+	}
+	return nil, nil
+}
+
+// unescapeSegment unescapes the escaped path segment.
+// The segment is returned as it is if it is not escaped correctly.
+func unescapeSegment(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// lookup returns the route of the node for the method.
+func (n *routeNode) lookup(method string, allow map[string]bool) *compiledRoute {
+	if len(n.routes) == 0 {
+		return nil
+	}
+	if route := n.routes[method]; route != nil {
+		return route
+	}
+	if route := n.routes[""]; route != nil {
+		return route
+	}
+	if method == http.MethodHead {
+		if route := n.routes[http.MethodGet]; route != nil {
+			return route
+		}
+	}
+	for m := range n.routes {
+		allow[m] = true
+		if m == http.MethodGet {
+			allow[http.MethodHead] = true
+		}
+	}
+	return nil
+}
+
+type routeKey struct{}
+
+// routeMatch is the result of routing stored in the request context.
+// This is stored as a pointer so that middleware wrapping the router can install it
+// in advance and see the result after the router returned.
+type routeMatch struct {
+	pattern string
+	names   []string
+	values  []string
+}
+
+// withRouteMatch returns the request which has the routeMatch in its context.
+// The routeMatch already installed in the context is reused.
+func withRouteMatch(r *http.Request) (*http.Request, *routeMatch) {
+	if m, ok := r.Context().Value(routeKey{}).(*routeMatch); ok {
+		return r, m
+	}
+	m := &routeMatch{}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, m)), m
+}
+
+// withRouterMatch returns the request which has the routeMatch for a router or a mux in its context.
+// The routeMatch installed in advance by middleware wrapping the router is reused,
+// but a new one is installed if the request was already routed by an outer router or mux,
+// so that nested routers do not overwrite the result of the outer one.
+func withRouterMatch(r *http.Request) (*http.Request, *routeMatch) {
+	if m, ok := r.Context().Value(routeKey{}).(*routeMatch); ok && m.pattern == "" {
+		return r, m
+	}
+	m := &routeMatch{}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, m)), m
+}
+
+/*
+PathParam returns the value of the path parameter of the route which matched the request.
+If the parameter is not found, r.PathValue() is returned,
+so this works also for the patterns of http.ServeMux and Mux.
+For wildcards, the rest of the path without the leading slash is returned.
+
+    router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+        id := chainist.PathParam(r, "id")
+    })
+*/
+func PathParam(r *http.Request, name string) string {
+	if m, ok := r.Context().Value(routeKey{}).(*routeMatch); ok {
+		for i, n := range m.names {
+			if n == name && i < len(m.values) {
+				return m.values[i]
+			}
+		}
+	}
+	return r.PathValue(name)
+}
+
+/*
+RoutePattern returns the pattern of the route which matched the request, e.g. "/users/{id}".
+For Mux, the pattern of http.ServeMux is returned, e.g. "GET /users/{id}".
+Empty string is returned if no routes matched.
+This can be used to label metrics without high cardinality.
+
+    pattern := chainist.RoutePattern(r)
+*/
+func RoutePattern(r *http.Request) string {
+	m, ok := r.Context().Value(routeKey{}).(*routeMatch)
+	if !ok {
+		return ""
+	}
+	return m.pattern
+}
@@ -0,0 +1,245 @@
+package chainist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveRouter(h http.Handler, method string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func paramHandlerFunc(names ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := RoutePattern(r)
+		for _, n := range names {
+			s += " " + n + "=" + PathParam(r, n)
+		}
+		if _, err := w.Write([]byte(s)); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestRouterMatch(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/", paramHandlerFunc())
+	rt.Get("/users", paramHandlerFunc())
+	rt.Get("/users/me", paramHandlerFunc())
+	rt.Get("/users/{id}", paramHandlerFunc("id"))
+	rt.Post("/users/{name}", paramHandlerFunc("name"))
+	rt.Get("/users/{id}/posts/{post}", paramHandlerFunc("id", "post"))
+	rt.Get("/files/{path...}", paramHandlerFunc("path"))
+	rt.Handle("", "/any", paramHandlerFunc())
+
+	{
+		w := serveRouter(rt, http.MethodGet, "/")
+		assert.Equal(t, "/", w.Body.String())
+	}
+	{
+		w := serveRouter(rt, http.MethodGet, "/users")
+		assert.Equal(t, "/users", w.Body.String())
+	}
+	{
+		// static segments precede parameters
+		w := serveRouter(rt, http.MethodGet, "/users/me")
+		assert.Equal(t, "/users/me", w.Body.String())
+	}
+	{
+		w := serveRouter(rt, http.MethodGet, "/users/42")
+		assert.Equal(t, "/users/{id} id=42", w.Body.String())
+	}
+	{
+		// falls back to the parameter when the static route does not have the method
+		w := serveRouter(rt, http.MethodPost, "/users/me")
+		assert.Equal(t, "/users/{name} name=me", w.Body.String())
+	}
+	{
+		w := serveRouter(rt, http.MethodGet, "/users/42/posts/7")
+		assert.Equal(t, "/users/{id}/posts/{post} id=42 post=7", w.Body.String())
+	}
+	{
+		w := serveRouter(rt, http.MethodGet, "/files/a/b.txt")
+		assert.Equal(t, "/files/{path...} path=a/b.txt", w.Body.String())
+		w = serveRouter(rt, http.MethodGet, "/files/")
+		assert.Equal(t, "/files/{path...} path=", w.Body.String())
+	}
+	{
+		// encoded slashes are kept in parameter values
+		w := serveRouter(rt, http.MethodGet, "/users/a%2Fb")
+		assert.Equal(t, "/users/{id} id=a/b", w.Body.String())
+		w = serveRouter(rt, http.MethodGet, "/files/a%2Fb/c%20d")
+		assert.Equal(t, "/files/{path...} path=a/b/c d", w.Body.String())
+		w = serveRouter(rt, http.MethodGet, "/users/%6De")
+		assert.Equal(t, "/users/me", w.Body.String())
+	}
+	{
+		w := serveRouter(rt, http.MethodDelete, "/any")
+		assert.Equal(t, "/any", w.Body.String())
+	}
+	{
+		// HEAD falls back to GET
+		w := serveRouter(rt, http.MethodHead, "/users")
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	{
+		w := serveRouter(rt, http.MethodGet, "/users/")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = serveRouter(rt, http.MethodGet, "/users/42/posts")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = serveRouter(rt, http.MethodGet, "/files")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+	{
+		w := serveRouter(rt, http.MethodPut, "/users/42")
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, HEAD, POST", w.Header().Get("Allow"))
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	{
+		rt := NewRouter(writeMiddleware("root"))
+		rt.NotFound = http.HandlerFunc(handlerFunc1)
+		rt.MethodNotAllowed = http.HandlerFunc(handlerFunc2)
+		rt.Get("/users", paramHandlerFunc())
+		assert.Equal(t, "rootf1", serveRouter(rt, http.MethodGet, "/").Body.String())
+		w := serveRouter(rt, http.MethodPost, "/users")
+		assert.Equal(t, "rootf2", w.Body.String())
+		assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+	}
+	{
+		rt := NewRouter()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.URL.Path = "*"
+		rt.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRouterChain(t *testing.T) {
+	rt := NewRouter(writeMiddleware("root-"))
+	rt.Get("/", paramHandlerFunc())
+
+	api := rt.Group("/api/", writeMiddleware("api-"))
+	api.Chain().AppendPreFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte("pre-")); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	users := api.Group("/users/{id}").Use(writeMiddleware("users-"))
+	route := users.Get("/posts", paramHandlerFunc("id")).Use(writeMiddleware("route-"))
+
+	assert.Equal(t, http.MethodGet, route.Method())
+	assert.Equal(t, "/api/users/{id}/posts", route.Pattern())
+	assert.Equal(t, 1, route.Chain().Len())
+	assert.Same(t, rt.root.chain, rt.Chain())
+	assert.Equal(t, 0, rt.Group("/nil", nil).Chain().Len())
+	assert.Equal(t, 0, NewRouter(nil).Chain().Len())
+
+	{
+		w := serveRouter(rt, http.MethodGet, "/")
+		assert.Equal(t, "root-/", w.Body.String())
+	}
+	{
+		w := serveRouter(rt, http.MethodGet, "/api/users/1/posts")
+		assert.Equal(t, "root-api-pre-users-route-/api/users/{id}/posts id=1", w.Body.String())
+	}
+}
+
+func TestRouterBuild(t *testing.T) {
+	{
+		rt := NewRouter()
+		rt.Get("/", paramHandlerFunc())
+		h, err := rt.Build()
+		assert.Nil(t, err)
+		assert.Same(t, rt, h)
+		assert.Panics(t, func() { rt.Get("/late", paramHandlerFunc()) })
+	}
+	{
+		rt := NewRouter()
+		rt.Chain().AppendNamed("a", plainMiddleware).After("a", "missing")
+		rt.Get("/", paramHandlerFunc())
+		h, err := rt.Build()
+		assert.Nil(t, h)
+		assert.True(t, errors.Is(err, ErrMissingDependency))
+		assert.Panics(t, func() { serveRouter(rt, http.MethodGet, "/") })
+	}
+}
+
+func TestRouterRegister(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/users/{id}", paramHandlerFunc())
+
+	patterns := []string{
+		"users",
+		"/users/{id",
+		"/users/x{id}",
+		"/files/{path...}/x",
+		"/users/{}",
+		"/users/{a-b}",
+		"/users/{id}/{id}",
+		"/users/{name}",
+	}
+	for _, p := range patterns {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				assert.True(t, errors.Is(err, ErrInvalidRoute), p)
+			}()
+			rt.Get(p, paramHandlerFunc())
+		}()
+	}
+	assert.Panics(t, func() { rt.Get("/nil", nil) })
+	assert.NotPanics(t, func() { rt.Post("/users/{name}", paramHandlerFunc()) })
+}
+
+func TestRouterNested(t *testing.T) {
+	var outer, outerID string
+	inner := NewRouter()
+	inner.Get("/x/{id}", paramHandlerFunc("id"))
+
+	m := NewMux(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			outer = RoutePattern(r)
+		})
+	})
+	m.Handle("GET /x/{name}", nil, func(w http.ResponseWriter, r *http.Request) {
+		inner.ServeHTTP(w, r)
+		outerID = PathParam(r, "id")
+	})
+
+	w := serveRouter(m, http.MethodGet, "/x/1")
+	assert.Equal(t, "/x/{id} id=1", w.Body.String())
+	assert.Equal(t, "GET /x/{name}", outer)
+	assert.Equal(t, "", outerID)
+}
+
+func TestPathParam(t *testing.T) {
+	{
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		assert.Equal(t, "", PathParam(r, "id"))
+		assert.Equal(t, "", RoutePattern(r))
+	}
+	{
+		// route match installed outside the router
+		m := &routeMatch{}
+		r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, m))
+		rt := NewRouter()
+		rt.Get("/users/{id}", paramHandlerFunc("id"))
+		rt.ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, "/users/{id}", RoutePattern(r))
+		assert.Equal(t, "1", PathParam(r, "id"))
+		assert.Equal(t, "", PathParam(r, "name"))
+	}
+}
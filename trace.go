@@ -0,0 +1,197 @@
+package chainist
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Span is the record of an execution of a middleware in a chain.
+type Span struct {
+	// Name is the name of the middleware.
+	// If the middleware is not named, this is derived from the symbol of the function
+	// in the same way as Entry.Name.
+	Name string
+
+	// Position is the position of the middleware in Chain.Middleware.
+	Position int
+
+	// Enter and Exit are the time when the middleware was invoked and returned.
+	// Exit is zero while the middleware is running.
+	Enter time.Time
+	Exit  time.Time
+
+	// CalledNext reports whether the middleware invoked the succeeding handler.
+	CalledNext bool
+
+	// Next is the time spent in the succeeding handlers.
+	Next time.Duration
+
+	// Status is the status code of the response observed when the middleware returned.
+	// This is 0 if the header was not written at that time.
+	Status int
+}
+
+// Duration returns the time spent in the middleware including the succeeding handlers.
+func (s Span) Duration() time.Duration {
+	if s.Exit.IsZero() {
+		return 0
+	}
+	return s.Exit.Sub(s.Enter)
+}
+
+// Self returns the time spent in the middleware excluding the succeeding handlers.
+func (s Span) Self() time.Duration {
+	return s.Duration() - s.Next
+}
+
+// Trace is the per-request record of the execution of middleware.
+// Spans are added in the order middleware is invoked.
+// Trace is safe for concurrent use.
+type Trace struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+// Spans returns a copy of the spans recorded so far.
+func (t *Trace) Spans() []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make([]Span, len(t.spans))
+	copy(spans, t.spans)
+	return spans
+}
+
+// enter adds a new span and returns the index of it.
+func (t *Trace) enter(name string, pos int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, Span{Name: name, Position: pos, Enter: time.Now()})
+	return len(t.spans) - 1
+}
+
+// update modifies the span at the index i.
+func (t *Trace) update(i int, f func(s *Span)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f(&t.spans[i])
+}
+
+type traceKey struct{}
+
+type spanKey struct{}
+
+/*
+TraceFrom returns the trace of the request stored in the given context.
+nil is returned if tracing is not enabled with EnableTracing().
+
+    chain.EnableTracing(nil)
+    chain.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+        for _, s := range chainist.TraceFrom(r.Context()).Spans() {
+            log.Println(s.Name, s.Self())
+        }
+    })
+*/
+func TraceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+/*
+EnableTracing makes the chain record the execution of every middleware in Chain.Middleware.
+The records can be obtained with TraceFrom() from the request context.
+If the report function is not nil, it is called with the trace after the chain returned.
+
+    chain.EnableTracing(func(r *http.Request, t *chainist.Trace) {
+        for _, s := range t.Spans() {
+            log.Printf("%d %s %s", s.Position, s.Name, s.Self())
+        }
+    })
+*/
+func (c *Chain) EnableTracing(report func(r *http.Request, t *Trace)) *Chain {
+	c, done := c.mutate()
+	defer done()
+	c.tracing = true
+	c.traceReport = report
+	return c
+}
+
+/*
+DisableTracing disables tracing enabled with EnableTracing().
+
+    chain.DisableTracing()
+*/
+func (c *Chain) DisableTracing() *Chain {
+	c, done := c.mutate()
+	defer done()
+	c.tracing = false
+	c.traceReport = nil
+	return c
+}
+
+// traceRoot stores a trace in the request context.
+// Trace is shared if the request is already traced by an outer chain.
+func traceRoot(report func(r *http.Request, t *Trace), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := TraceFrom(r.Context())
+		if t == nil {
+			t = &Trace{}
+			r = r.WithContext(context.WithValue(r.Context(), traceKey{}, t))
+		}
+		next.ServeHTTP(w, r)
+		if report != nil {
+			report(r, t)
+		}
+	})
+}
+
+// traceMiddleware wraps the middleware to record its execution.
+func traceMiddleware(name string, pos int, m Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		if next != nil {
+			next = traceNext(next)
+		}
+		h := m(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := TraceFrom(r.Context())
+			if t == nil || h == nil {
+				if h != nil {
+					h.ServeHTTP(w, r)
+				}
+				return
+			}
+			i := t.enter(name, pos)
+			defer func() {
+				info, _ := ResponseInfo(w)
+				t.update(i, func(s *Span) {
+					s.Exit = time.Now()
+					s.Status = info.Status
+				})
+			}()
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), spanKey{}, i)))
+		})
+	}
+}
+
+// traceNext records the invocation of the next handler
+// into the span of the middleware which invoked it.
+func traceNext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := TraceFrom(r.Context())
+		i, ok := r.Context().Value(spanKey{}).(int)
+		if t == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		defer func() {
+			elapsed := time.Since(start)
+			t.update(i, func(s *Span) {
+				s.CalledNext = true
+				s.Next += elapsed
+			})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
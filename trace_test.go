@@ -0,0 +1,127 @@
+package chainist
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sleepMiddleware(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(d)
+			if next != nil {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func TestSpan(t *testing.T) {
+	{
+		s := Span{Enter: time.Now()}
+		assert.Equal(t, time.Duration(0), s.Duration())
+		assert.Equal(t, time.Duration(0), s.Self())
+	}
+	{
+		now := time.Now()
+		s := Span{Enter: now, Exit: now.Add(50 * time.Millisecond), Next: 10 * time.Millisecond}
+		assert.Equal(t, 50*time.Millisecond, s.Duration())
+		assert.Equal(t, 40*time.Millisecond, s.Self())
+	}
+}
+
+func TestTraceFrom(t *testing.T) {
+	assert.Nil(t, TraceFrom(context.Background()))
+	tr := &Trace{}
+	assert.Same(t, tr, TraceFrom(context.WithValue(context.Background(), traceKey{}, tr)))
+}
+
+func TestEnableTracing(t *testing.T) {
+	{
+		c := NewChain()
+		c.EnableTracing(func(*http.Request, *Trace) {})
+		assert.True(t, c.tracing)
+		assert.NotNil(t, c.traceReport)
+		c.DisableTracing()
+		assert.False(t, c.tracing)
+		assert.Nil(t, c.traceReport)
+	}
+	{
+		var spans []Span
+		var inner *Trace
+		c := NewChain()
+		c.AppendNamed("slow", sleepMiddleware(20*time.Millisecond))
+		c.AppendGuard(func(w http.ResponseWriter, r *http.Request) bool {
+			inner = TraceFrom(r.Context())
+			w.WriteHeader(http.StatusForbidden)
+			return false
+		})
+		c.AppendNamed("unreached", handler1.Middleware)
+		c.EnableTracing(func(r *http.Request, tr *Trace) {
+			assert.Same(t, inner, tr)
+			spans = tr.Spans()
+		})
+
+		w := serveRecorder(c.ChainFunc(handlerFunc1))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		assert.Equal(t, 2, len(spans))
+		assert.Equal(t, "slow", spans[0].Name)
+		assert.Equal(t, 0, spans[0].Position)
+		assert.True(t, spans[0].CalledNext)
+		assert.Equal(t, http.StatusForbidden, spans[0].Status)
+		assert.GreaterOrEqual(t, spans[0].Self(), 20*time.Millisecond)
+		assert.GreaterOrEqual(t, spans[0].Duration(), spans[1].Duration())
+
+		assert.Equal(t, c.Describe().Entries[1].Name, spans[1].Name)
+		assert.Contains(t, spans[1].Name, "TestEnableTracing.func")
+		assert.Equal(t, 1, spans[1].Position)
+		assert.False(t, spans[1].CalledNext)
+		assert.Equal(t, http.StatusForbidden, spans[1].Status)
+		assert.False(t, spans[1].Exit.IsZero())
+	}
+	{
+		// nested chains share the trace
+		var spans []Span
+		inner := NewChain().AppendNamed("inner", handler1.PreMiddleware).EnableTracing(nil)
+		outer := NewChain().AppendNamed("outer", handler2.PreMiddleware)
+		outer.EnableTracing(func(r *http.Request, tr *Trace) {
+			spans = tr.Spans()
+		})
+		h := outer.ChainFunc(inner.ChainFunc(nil).ServeHTTP)
+
+		w := serveRecorder(h)
+		assert.Equal(t, "h2h1", w.Body.String())
+		assert.Equal(t, 2, len(spans))
+		assert.Equal(t, "outer", spans[0].Name)
+		assert.Equal(t, "inner", spans[1].Name)
+		assert.True(t, spans[0].CalledNext)
+		assert.False(t, spans[1].CalledNext)
+	}
+	{
+		// unnamed middleware is traced with the name derived from the symbol
+		var spans []Span
+		c := NewChain(handler1.PreMiddleware, writeMiddleware("a"))
+		c.EnableTracing(func(r *http.Request, tr *Trace) {
+			spans = tr.Spans()
+		})
+
+		serveRecorder(c.ChainFunc(handlerFunc1))
+		assert.Equal(t, 2, len(spans))
+		assert.Equal(t, "github.com/t-katsumura/chainist.(*HandlerFuncWrapper).PreMiddleware", spans[0].Name)
+		assert.Equal(t, "github.com/t-katsumura/chainist.writeMiddleware.func1", spans[1].Name)
+		d := c.Describe()
+		assert.Equal(t, d.Entries[0].Name, spans[0].Name)
+		assert.Equal(t, d.Entries[1].Name, spans[1].Name)
+	}
+	{
+		// middleware chained without tracing works as usual
+		h := traceMiddleware("m", 0, handler1.PreMiddleware)(http.HandlerFunc(handlerFunc1))
+		w := serveRecorder(h)
+		assert.Equal(t, "h1f1", w.Body.String())
+	}
+}
@@ -0,0 +1,275 @@
+package chainist
+
+import "net/http"
+
+// RoundTripperFunc is the function type which implements http.RoundTripper.
+type RoundTripperFunc func(r *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(r).
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+/*
+TransportMiddleware is the middleware for http.RoundTripper.
+An example of basic definition of transport middleware is
+
+    func YourTransportMiddleware(next http.RoundTripper) http.RoundTripper {
+        return chainist.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+            // you can write your code here
+            resp, err := next.RoundTrip(r)
+            // you can write your code here
+            return resp, err
+        })
+    }
+*/
+type TransportMiddleware = GenericMiddleware[http.RoundTripper]
+
+/*
+TransportChain is the middleware chain for outbound requests.
+This is built on GenericChain for http.RoundTripper and provides the same Append/Insert/Extend/Join API as Chain.
+The round tripper at the edge of the chain is set with SetTransport().
+In addition, this provides functions executed before sending requests and after receiving responses,
+and uses http.DefaultTransport at the edge of the chain by default.
+
+    chain := chainist.NewTransportChain(logging, retry)
+    chain.AppendPreFunc(injectAuthHeader)
+
+    client := &http.Client{
+        Transport: chain.Chain(),
+    }
+*/
+type TransportChain struct {
+	GenericChain[http.RoundTripper]
+}
+
+/*
+NewTransportChain creates a new transport middleware chain.
+If nil is contained in the given arguments, nil is returned.
+
+    // logging is called at first, and retry at last
+    chain := chainist.NewTransportChain(logging, auth, retry)
+*/
+func NewTransportChain(ms ...TransportMiddleware) *TransportChain {
+	g := NewGenericChain(ms...)
+	if g == nil {
+		return nil
+	}
+	return &TransportChain{GenericChain: *g}
+}
+
+/*
+Append transport middleware at the last of the chain.
+If nil is given, then the chain will be returned without adding it.
+
+    chain := chainist.NewTransportChain()
+    chain.Append(logging).Append(retry)
+*/
+func (c *TransportChain) Append(m TransportMiddleware) *TransportChain {
+	c.GenericChain.Append(m)
+	return c
+}
+
+/*
+AppendPreFunc appends a function which is executed before sending requests.
+The function receives the clone of the request, so it can modify the request.
+If nil is given, then the chain will be returned as it is.
+
+    chain.AppendPreFunc(func(r *http.Request) {
+        r.Header.Set("Authorization", "Bearer "+token)
+    })
+*/
+func (c *TransportChain) AppendPreFunc(f TransportPreFunc) *TransportChain {
+	if f == nil {
+		return c
+	}
+	t := &TransportFuncWrapper{PreFunc: f}
+	return c.Append(t.Middleware)
+}
+
+/*
+AppendPostFunc appends a function which is executed after receiving responses.
+The function receives the response or the error returned by succeeding round trippers.
+If nil is given, then the chain will be returned as it is.
+
+    chain.AppendPostFunc(func(r *http.Request, resp *http.Response, err error) {
+        if err != nil {
+            log.Println(r.URL, err)
+            return
+        }
+        log.Println(r.URL, resp.StatusCode)
+    })
+*/
+func (c *TransportChain) AppendPostFunc(f TransportPostFunc) *TransportChain {
+	if f == nil {
+		return c
+	}
+	t := &TransportFuncWrapper{PostFunc: f}
+	return c.Append(t.Middleware)
+}
+
+/*
+Insert transport middleware at the designated position of the chain.
+If nil is given, then the chain will be returned without inserting it.
+Positions are handled in the same way as Chain.Insert().
+
+    // insert auth at the first of the chain
+    chain.Insert(auth, 0)
+*/
+func (c *TransportChain) Insert(m TransportMiddleware, i int) *TransportChain {
+	c.GenericChain.Insert(m, i)
+	return c
+}
+
+/*
+InsertPreFunc inserts a function which is executed before sending requests at the designated position of the chain.
+See AppendPreFunc() for the function and Insert() for the position.
+
+    chain.InsertPreFunc(injectAuthHeader, 0)
+*/
+func (c *TransportChain) InsertPreFunc(f TransportPreFunc, i int) *TransportChain {
+	if f == nil {
+		return c
+	}
+	t := &TransportFuncWrapper{PreFunc: f}
+	return c.Insert(t.Middleware, i)
+}
+
+/*
+InsertPostFunc inserts a function which is executed after receiving responses at the designated position of the chain.
+See AppendPostFunc() for the function and Insert() for the position.
+
+    chain.InsertPostFunc(logResponse, 0)
+*/
+func (c *TransportChain) InsertPostFunc(f TransportPostFunc, i int) *TransportChain {
+	if f == nil {
+		return c
+	}
+	t := &TransportFuncWrapper{PostFunc: f}
+	return c.Insert(t.Middleware, i)
+}
+
+/*
+Extend the chain with the given transport middleware.
+nil is ignored.
+
+    chain.Extend(logging, auth, retry)
+*/
+func (c *TransportChain) Extend(ms ...TransportMiddleware) *TransportChain {
+	c.GenericChain.Extend(ms...)
+	return c
+}
+
+/*
+ExtendPreFunc extends the chain with the given functions executed before sending requests.
+nil is ignored.
+
+    chain.ExtendPreFunc(injectAuthHeader, injectUserAgent)
+*/
+func (c *TransportChain) ExtendPreFunc(fs ...TransportPreFunc) *TransportChain {
+	for _, f := range fs {
+		c.AppendPreFunc(f)
+	}
+	return c
+}
+
+/*
+ExtendPostFunc extends the chain with the given functions executed after receiving responses.
+nil is ignored.
+
+    chain.ExtendPostFunc(logResponse, recordMetrics)
+*/
+func (c *TransportChain) ExtendPostFunc(fs ...TransportPostFunc) *TransportChain {
+	for _, f := range fs {
+		c.AppendPostFunc(f)
+	}
+	return c
+}
+
+/*
+SetHandler sets the round tripper at the edge of the chain.
+
+    chain.SetHandler(&http.Transport{MaxIdleConnsPerHost: 10})
+*/
+func (c *TransportChain) SetHandler(t http.RoundTripper) *TransportChain {
+	c.GenericChain.SetHandler(t)
+	return c
+}
+
+/*
+SetTransport sets the round tripper at the edge of the chain.
+This is the same as SetHandler().
+
+    chain.SetTransport(&http.Transport{MaxIdleConnsPerHost: 10})
+*/
+func (c *TransportChain) SetTransport(t http.RoundTripper) *TransportChain {
+	return c.SetHandler(t)
+}
+
+/*
+Join the transport middleware of the other chain at the last of the chain.
+The round tripper of the other chain is not used.
+If nil is given, then the chain will be returned as it is.
+
+    chain.Join(anotherChain)
+*/
+func (c *TransportChain) Join(o *TransportChain) *TransportChain {
+	if o == nil {
+		return c
+	}
+	c.GenericChain.Join(&o.GenericChain)
+	return c
+}
+
+/*
+Len gets the length of transport middleware chain.
+This contains the length of Middleware and the round tripper at the edge if it's already set.
+*/
+func (c *TransportChain) Len() int {
+	length := len(c.Middleware)
+	if c.Handler != nil {
+		length += 1
+	}
+	return length
+}
+
+/*
+Clone returns a copy of the chain.
+The copy does not share the slice of middleware with the chain, so modifying one does not affect the other.
+
+    admin := base.Clone().AppendPreFunc(injectAdminToken)
+*/
+func (c *TransportChain) Clone() *TransportChain {
+	return &TransportChain{GenericChain: *c.GenericChain.Clone()}
+}
+
+/*
+Chain returns a new round tripper which sends requests through the chain.
+This is the same as calling ChainTransport(nil).
+
+    client := &http.Client{
+        Transport: chain.Chain(),
+    }
+*/
+func (c *TransportChain) Chain() http.RoundTripper {
+	return c.ChainTransport(nil)
+}
+
+/*
+ChainTransport returns a new round tripper which sends requests through the chain.
+If the given round tripper is not nil, it is used instead of chain.Handler which is set with `SetTransport()`.
+If both are nil, http.DefaultTransport is used.
+
+    client := &http.Client{
+        Transport: chain.ChainTransport(&http.Transport{}),
+    }
+*/
+func (c *TransportChain) ChainTransport(t http.RoundTripper) http.RoundTripper {
+	if t == nil {
+		t = c.Handler
+	}
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	return c.Then(t)
+}
@@ -0,0 +1,169 @@
+package chainist
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// echoTransport responds the values of the "X-Trace" header of requests as the body.
+var echoTransport = RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(strings.Join(r.Header.Values("X-Trace"), ","))),
+		Request:    r,
+	}, nil
+})
+
+func traceTransport(s string) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.Header.Add("X-Trace", s)
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+func roundTrip(t *testing.T, rt http.RoundTripper) string {
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	defer res.Body.Close()
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestRoundTripperFunc(t *testing.T) {
+	assert.Equal(t, "", roundTrip(t, echoTransport))
+}
+
+func TestNewTransportChain(t *testing.T) {
+	{
+		c := NewTransportChain()
+		assert.Equal(t, 0, c.Len())
+	}
+	{
+		c := NewTransportChain(traceTransport("a"), traceTransport("b"))
+		assert.Equal(t, 2, c.Len())
+		c.SetTransport(echoTransport)
+		assert.Equal(t, 3, c.Len())
+		assert.Equal(t, "a,b", roundTrip(t, c.Chain()))
+	}
+	{
+		c := NewTransportChain(traceTransport("a"), nil)
+		assert.Nil(t, c)
+	}
+}
+
+func TestTransportChainAppend(t *testing.T) {
+	c := NewTransportChain()
+	c.Append(nil).AppendPreFunc(nil).AppendPostFunc(nil)
+	assert.Equal(t, 0, c.Len())
+
+	var got string
+	c.Append(traceTransport("a"))
+	c.AppendPreFunc(func(r *http.Request) {
+		r.Header.Add("X-Trace", "pre")
+	})
+	c.AppendPostFunc(func(r *http.Request, resp *http.Response, err error) {
+		got = strings.Join(r.Header.Values("X-Trace"), ",")
+	})
+	c.Append(traceTransport("b"))
+	assert.Equal(t, 4, c.Len())
+	assert.Equal(t, "a,pre,b", roundTrip(t, c.ChainTransport(echoTransport)))
+	assert.Equal(t, "a,pre", got)
+}
+
+func TestTransportChainInsert(t *testing.T) {
+	c := NewTransportChain()
+	c.Insert(nil, 0).InsertPreFunc(nil, 0).InsertPostFunc(nil, 0)
+	assert.Equal(t, 0, c.Len())
+
+	var called bool
+	c.Insert(traceTransport("a"), 0)
+	c.Insert(traceTransport("b"), -1)
+	c.InsertPreFunc(func(r *http.Request) {
+		r.Header.Add("X-Trace", "pre")
+	}, 1)
+	c.InsertPostFunc(func(r *http.Request, resp *http.Response, err error) {
+		called = true
+	}, 99)
+	c.SetTransport(echoTransport)
+	assert.Equal(t, "b,pre,a", roundTrip(t, c.Chain()))
+	assert.True(t, called)
+}
+
+func TestTransportChainExtend(t *testing.T) {
+	var posts int
+	post := func(r *http.Request, resp *http.Response, err error) {
+		posts++
+	}
+	c := NewTransportChain()
+	c.Extend(traceTransport("a"), nil, traceTransport("b"))
+	c.ExtendPreFunc(func(r *http.Request) {
+		r.Header.Add("X-Trace", "pre")
+	}, nil)
+	c.ExtendPostFunc(post, nil, post)
+	assert.Equal(t, 5, c.Len())
+	assert.Equal(t, "a,b,pre", roundTrip(t, c.ChainTransport(echoTransport)))
+	assert.Equal(t, 2, posts)
+}
+
+func TestTransportChainJoin(t *testing.T) {
+	c := NewTransportChain(traceTransport("a"))
+	c.Join(nil)
+	assert.Equal(t, 1, c.Len())
+	c.Join(NewTransportChain(traceTransport("b")).SetTransport(http.DefaultTransport))
+	assert.Equal(t, 2, c.Len())
+	assert.Nil(t, c.Handler)
+	assert.Equal(t, "a,b", roundTrip(t, c.ChainTransport(echoTransport)))
+}
+
+func TestTransportChainClone(t *testing.T) {
+	base := NewTransportChain(traceTransport("a")).SetTransport(echoTransport)
+	c := base.Clone().Extend(traceTransport("b")).AppendPreFunc(func(r *http.Request) {
+		r.Header.Add("X-Trace", "pre")
+	})
+	assert.Equal(t, 2, base.Len())
+	assert.Equal(t, "a", roundTrip(t, base.Chain()))
+	assert.Equal(t, "a,b,pre", roundTrip(t, c.Chain()))
+}
+
+func TestTransportChainChain(t *testing.T) {
+	{
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := w.Write([]byte(r.Header.Get("X-Trace"))); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer s.Close()
+
+		// http.DefaultTransport is used by default
+		client := &http.Client{Transport: NewTransportChain(traceTransport("a")).Chain()}
+		res, err := client.Get(s.URL)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, "a", string(body))
+	}
+	{
+		var gotErr error
+		fail := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, errors.New("fail")
+		})
+		c := NewTransportChain().AppendPostFunc(func(r *http.Request, resp *http.Response, err error) {
+			gotErr = err
+		})
+		c.Middleware = append(c.Middleware, nil)
+		_, err := c.ChainTransport(fail).RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+		assert.EqualError(t, err, "fail")
+		assert.EqualError(t, gotErr, "fail")
+	}
+}
@@ -0,0 +1,149 @@
+package chainist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+/*
+Key is the typed key of request-scoped values.
+Values set with a key can be obtained in the succeeding middleware, handlers
+and post-executable functions of the chain.
+Keys are compared by their pointers, so create them once with NewKey() and share them.
+
+    var userKey = chainist.NewKey[*User]("user")
+
+    chain.AppendPreFunc(func(w http.ResponseWriter, r *http.Request) {
+        userKey.Set(r, findUser(r))
+    })
+    chain.SetHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        user := userKey.MustGet(r)
+    })
+*/
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new key of values of the type T.
+// The name is used only for error messages.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// String returns the name of the key.
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+/*
+Set sets the value to the request.
+The chains created with Chain() or ChainFunc() install a mutable value store in the request context,
+so the value is set to the store and the given request is returned as it is.
+This enables the functions which can not replace the request, such as pre-executable functions,
+to pass values to the succeeding handlers.
+Otherwise, a new request which has the value in its context is returned.
+
+    r = userKey.Set(r, user)
+*/
+func (k *Key[T]) Set(r *http.Request, v T) *http.Request {
+	s, ok := r.Context().Value(valuesKey{}).(*valueStore)
+	if !ok {
+		s = &valueStore{}
+		r = r.WithContext(context.WithValue(r.Context(), valuesKey{}, s))
+	}
+	s.set(k, v)
+	return r
+}
+
+// Get returns the value set to the request.
+// false is returned if the value is not set.
+func (k *Key[T]) Get(r *http.Request) (T, bool) {
+	return k.Value(r.Context())
+}
+
+// MustGet returns the value set to the request.
+// This panics if the value is not set.
+func (k *Key[T]) MustGet(r *http.Request) T {
+	v, ok := k.Get(r)
+	if !ok {
+		panic(fmt.Sprintf("chainist: value of key %q is not set", k.name))
+	}
+	return v
+}
+
+// Value returns the value set to the request of the context.
+// false is returned if the value is not set.
+func (k *Key[T]) Value(ctx context.Context) (T, bool) {
+	var zero T
+	s, ok := ctx.Value(valuesKey{}).(*valueStore)
+	if !ok {
+		return zero, false
+	}
+	v, ok := s.get(k)
+	if !ok {
+		return zero, false
+	}
+	// v is nil when nil is set for interface types
+	t, _ := v.(T)
+	return t, true
+}
+
+type valuesKey struct{}
+
+// valueStore is the mutable store of request-scoped values.
+// This is safe for concurrent use because handlers can spawn goroutines.
+type valueStore struct {
+	mu     sync.RWMutex
+	values map[any]any
+}
+
+func (s *valueStore) set(k, v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = map[any]any{}
+	}
+	s.values[k] = v
+}
+
+func (s *valueStore) get(k any) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[k]
+	return v, ok
+}
+
+// valuesMiddleware installs the value store into the request context.
+// The store already installed by outer chains is reused.
+func valuesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(valuesKey{}).(*valueStore); !ok {
+			r = r.WithContext(context.WithValue(r.Context(), valuesKey{}, &valueStore{}))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// finalRequestKey is the key of the request which reached the edge of the chain.
+var finalRequestKey = NewKey[*http.Request]("final request")
+
+// finalRequestMiddleware records the request given to the handler at the edge of the chain.
+func finalRequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(valuesKey{}).(*valueStore); ok {
+			finalRequestKey.Set(r, r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// finalRequest returns the request which reached the edge of the chain.
+// The given request is returned if it is not recorded.
+func finalRequest(r *http.Request) *http.Request {
+	if fr, ok := finalRequestKey.Get(r); ok {
+		return fr
+	}
+	return r
+}
@@ -0,0 +1,132 @@
+package chainist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	nameKey := NewKey[string]("name")
+	countKey := NewKey[int]("count")
+	assert.Equal(t, "name", nameKey.String())
+	{
+		// outside chains
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		_, ok := nameKey.Get(r)
+		assert.False(t, ok)
+		r2 := nameKey.Set(r, "alice")
+		assert.NotSame(t, r, r2)
+		v, ok := nameKey.Get(r2)
+		assert.True(t, ok)
+		assert.Equal(t, "alice", v)
+		_, ok = nameKey.Get(r)
+		assert.False(t, ok)
+
+		// the store is reused
+		r3 := countKey.Set(r2, 3)
+		assert.Same(t, r2, r3)
+		assert.Equal(t, 3, countKey.MustGet(r2))
+		assert.Equal(t, "alice", nameKey.MustGet(r3))
+	}
+	{
+		// keys with the same name are different
+		otherKey := NewKey[string]("name")
+		r := nameKey.Set(httptest.NewRequest(http.MethodGet, "/", nil), "alice")
+		_, ok := otherKey.Get(r)
+		assert.False(t, ok)
+	}
+	{
+		assert.PanicsWithValue(t, `chainist: value of key "count" is not set`, func() {
+			countKey.MustGet(httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	}
+	{
+		_, ok := nameKey.Value(context.Background())
+		assert.False(t, ok)
+		r := nameKey.Set(httptest.NewRequest(http.MethodGet, "/", nil), "bob")
+		v, ok := nameKey.Value(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "bob", v)
+	}
+	{
+		// nil can be set for interface types
+		errKey := NewKey[error]("err")
+		r := errKey.Set(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+		v, ok := errKey.Get(r)
+		assert.True(t, ok)
+		assert.Nil(t, v)
+		errKey.Set(r, context.Canceled)
+		assert.Equal(t, context.Canceled, errKey.MustGet(r))
+	}
+}
+
+func TestKeyInChain(t *testing.T) {
+	nameKey := NewKey[string]("name")
+	{
+		// pre functions pass values to the succeeding handlers
+		c := NewChain()
+		c.AppendPreFunc(func(w http.ResponseWriter, r *http.Request) {
+			nameKey.Set(r, "alice")
+		})
+		c.AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(":" + nameKey.MustGet(r)))
+		})
+		w := serveRecorder(c.ChainFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(nameKey.MustGet(r)))
+		}))
+		assert.Equal(t, "alice:alice", w.Body.String())
+	}
+	{
+		// values are shared with nested chains and not shared between requests
+		inner := NewChain().AppendPreFunc(func(w http.ResponseWriter, r *http.Request) {
+			nameKey.Set(r, "inner")
+		}).ChainFunc(handlerFunc1)
+		outer := NewChain().AppendPostFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, _ := nameKey.Get(r)
+			_, _ = w.Write([]byte(v))
+		})
+		outer.AppendPreFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := nameKey.Get(r)
+			assert.False(t, ok)
+		})
+		h := outer.ChainFunc(inner.ServeHTTP)
+		assert.Equal(t, "f1inner", serveRecorder(h).Body.String())
+		assert.Equal(t, "f1inner", serveRecorder(h).Body.String())
+	}
+	{
+		// values set in goroutines
+		countKey := NewKey[int]("count")
+		c := NewChain().AppendPreFunc(func(w http.ResponseWriter, r *http.Request) {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					countKey.Set(r, i)
+					countKey.Get(r)
+				}(i)
+			}
+			wg.Wait()
+		})
+		serveRecorder(c.ChainFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := countKey.Get(r)
+			assert.True(t, ok)
+		}))
+	}
+	{
+		c := NewErrChain().AppendPreFunc(func(w http.ResponseWriter, r *http.Request) error {
+			nameKey.Set(r, "err")
+			return nil
+		})
+		w := serveRecorder(c.ChainFunc(func(w http.ResponseWriter, r *http.Request) error {
+			_, err := w.Write([]byte(nameKey.MustGet(r)))
+			return err
+		}))
+		assert.Equal(t, "err", w.Body.String())
+	}
+}
@@ -23,8 +23,11 @@ func (h *HandlerFuncWrapper) PreMiddleware(next http.Handler) http.Handler {
 // Wrap http handler function as http handler.
 // Wrapped function is executed after invoking proceeding handlers.
 // This is what `Post` means.
+// The response written by proceeding handlers can be obtained with ResponseInfo()
+// in the wrapped function.
 func (h *HandlerFuncWrapper) PostMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w = recordWriter(w)
 		if next != nil {
 			next.ServeHTTP(w, r)
 		}
@@ -34,7 +37,111 @@ func (h *HandlerFuncWrapper) PostMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// Wrap http handler function as http handler.
+// Wrapped function is executed after invoking proceeding handlers same as `PostMiddleware`,
+// but receives the final request which reached the edge of the chain
+// instead of the request given to this handler.
+// The original request is given if the final request is unknown,
+// e.g. the handler is not used in the chains created with Chain() or ChainFunc().
+func (h *HandlerFuncWrapper) FinalPostMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w = recordWriter(w)
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+		if h.HandlerFunc != nil {
+			h.HandlerFunc(w, finalRequest(r))
+		}
+	})
+}
+
 // Alias for `PreMiddleware`
 func (h *HandlerFuncWrapper) Middleware(next http.Handler) http.Handler {
 	return h.PreMiddleware(next)
 }
+
+// RequestFunc is a http handler function which returns the request
+// passed to proceeding handlers. This can be used to attach context values,
+// rewrite URLs or set headers for proceeding handlers.
+// Returning nil means the given request is passed as it is.
+type RequestFunc func(w http.ResponseWriter, r *http.Request) *http.Request
+
+type RequestFuncWrapper struct {
+	RequestFunc RequestFunc
+}
+
+// Wrap request function as http handler.
+// Wrapped function is executed before invoking proceeding handlers
+// and the returned request is passed to them.
+func (h *RequestFuncWrapper) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.RequestFunc != nil {
+			if nr := h.RequestFunc(w, r); nr != nil {
+				r = nr
+			}
+		}
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// GuardFunc is a http handler function which reports whether
+// proceeding handlers should be invoked or not.
+// Guard functions are expected to write the response by themselves
+// when they return false, e.g. responding 401 Unauthorized.
+type GuardFunc func(w http.ResponseWriter, r *http.Request) bool
+
+type GuardFuncWrapper struct {
+	GuardFunc GuardFunc
+}
+
+// Wrap guard function as http handler.
+// Wrapped function is executed before invoking proceeding handlers
+// and the chain is aborted when the function returns false.
+func (g *GuardFuncWrapper) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.GuardFunc != nil && !g.GuardFunc(w, r) {
+			return
+		}
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// TransportPreFunc is a function executed before sending requests with TransportChain.
+// The request given to the function is a clone of the original one,
+// so headers can be modified, e.g. injecting an authorization header.
+type TransportPreFunc func(r *http.Request)
+
+// TransportPostFunc is a function executed after receiving responses with TransportChain.
+// Either the response or the error returned by succeeding round trippers is given.
+type TransportPostFunc func(r *http.Request, resp *http.Response, err error)
+
+type TransportFuncWrapper struct {
+	PreFunc  TransportPreFunc
+	PostFunc TransportPostFunc
+}
+
+// Wrap transport functions as http round tripper.
+// PreFunc is executed before invoking proceeding round trippers with the clone of the request,
+// and PostFunc is executed after them with the response.
+// http.DefaultTransport is used if next is nil.
+func (t *TransportFuncWrapper) Middleware(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if t.PreFunc != nil {
+			// RoundTrippers must not modify the given request
+			r = r.Clone(r.Context())
+			t.PreFunc(r)
+		}
+		resp, err := next.RoundTrip(r)
+		if t.PostFunc != nil {
+			t.PostFunc(r, resp, err)
+		}
+		return resp, err
+	})
+}
@@ -219,3 +219,178 @@ func TestMiddleware(t *testing.T) {
 		assert.Equal(t, "t1t2", string(body))
 	}
 }
+
+func TestGuardMiddleware(t *testing.T) {
+	deny := func(w http.ResponseWriter, r *http.Request) bool {
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	allow := func(w http.ResponseWriter, r *http.Request) bool {
+		return true
+	}
+	{
+		g := &GuardFuncWrapper{
+			GuardFunc: nil,
+		}
+		s := httptest.NewServer(g.Middleware(http.HandlerFunc(test1)))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "t1", string(body))
+	}
+	{
+		g := &GuardFuncWrapper{
+			GuardFunc: allow,
+		}
+		s := httptest.NewServer(g.Middleware(nil))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, 200, res.StatusCode)
+	}
+	{
+		g := &GuardFuncWrapper{
+			GuardFunc: allow,
+		}
+		s := httptest.NewServer(g.Middleware(http.HandlerFunc(test1)))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "t1", string(body))
+	}
+	{
+		g := &GuardFuncWrapper{
+			GuardFunc: deny,
+		}
+		s := httptest.NewServer(g.Middleware(http.HandlerFunc(test1)))
+		defer s.Close()
+
+		res, err := http.Get(s.URL)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 401, res.StatusCode)
+		assert.Equal(t, "", string(body))
+	}
+}
+
+func TestTransportFuncWrapper(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(r.Header.Get("X-Test"))); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+	{
+		tw := &TransportFuncWrapper{}
+		req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+		res, err := tw.Middleware(nil).RoundTrip(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, 200, res.StatusCode)
+	}
+	{
+		var status int
+		tw := &TransportFuncWrapper{
+			PreFunc: func(r *http.Request) {
+				r.Header.Set("X-Test", "pre")
+			},
+			PostFunc: func(r *http.Request, resp *http.Response, err error) {
+				assert.Equal(t, "pre", r.Header.Get("X-Test"))
+				assert.NoError(t, err)
+				status = resp.StatusCode
+			},
+		}
+		req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+		res, err := tw.Middleware(http.DefaultTransport).RoundTrip(req)
+		assert.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		defer res.Body.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "pre", string(body))
+		// the original request is not modified
+		assert.Equal(t, "", req.Header.Get("X-Test"))
+	}
+}
+
+func TestRequestFuncWrapper(t *testing.T) {
+	pathHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	})
+	{
+		h := &RequestFuncWrapper{
+			RequestFunc: func(w http.ResponseWriter, r *http.Request) *http.Request {
+				r2 := r.Clone(r.Context())
+				r2.URL.Path = "/rewritten"
+				return r2
+			},
+		}
+		w := serveRecorder(h.Middleware(pathHandler))
+		assert.Equal(t, "/rewritten", w.Body.String())
+	}
+	{
+		// nil means the original request
+		h := &RequestFuncWrapper{
+			RequestFunc: func(w http.ResponseWriter, r *http.Request) *http.Request {
+				return nil
+			},
+		}
+		w := serveRecorder(h.Middleware(pathHandler))
+		assert.Equal(t, "/", w.Body.String())
+	}
+	{
+		h := &RequestFuncWrapper{}
+		assert.Equal(t, "/", serveRecorder(h.Middleware(pathHandler)).Body.String())
+		assert.Equal(t, 200, serveRecorder(h.Middleware(nil)).Code)
+	}
+}
+
+func TestFinalPostMiddleware(t *testing.T) {
+	var path string
+	h := &HandlerFuncWrapper{
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+		},
+	}
+	rewrite := &RequestFuncWrapper{
+		RequestFunc: func(w http.ResponseWriter, r *http.Request) *http.Request {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/rewritten"
+			return r2
+		},
+	}
+	{
+		// the original request is given without chains
+		serveRecorder(h.FinalPostMiddleware(rewrite.Middleware(http.HandlerFunc(test1))))
+		assert.Equal(t, "/", path)
+	}
+	{
+		c := NewChain(h.FinalPostMiddleware, rewrite.Middleware)
+		w := serveRecorder(c.ChainFunc(test1))
+		assert.Equal(t, "t1", w.Body.String())
+		assert.Equal(t, "/rewritten", path)
+	}
+}